@@ -0,0 +1,344 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/Alb3G/gator/internal/database"
+	rss "github.com/Alb3G/gator/internal/rss"
+	"github.com/Alb3G/gator/internal/service"
+	utils "github.com/Alb3G/gator/internal/utils"
+	"github.com/google/uuid"
+)
+
+func (s *Server) handleGetUsers(w http.ResponseWriter, r *http.Request) {
+	users, err := s.users.List(r.Context())
+	if err != nil {
+		respondWithServiceError(w, err)
+		return
+	}
+
+	response := []User{}
+	for _, user := range users {
+		response = append(response, UserFromDB(user))
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func (s *Server) handleCreateUser(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		UserName string `json:"user_name"`
+		Password string `json:"password"`
+	}
+	err := decodeJSON(r, &body)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+
+	user, err := s.users.Register(r.Context(), body.UserName, body.Password)
+	if err != nil {
+		respondWithServiceError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, UserFromDB(user))
+}
+
+// Logs a user in, the token of the session goes in the Authorization header
+// of the following requests.
+func (s *Server) handleCreateSession(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		UserName string `json:"user_name"`
+		Password string `json:"password"`
+	}
+	err := decodeJSON(r, &body)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+
+	user, token, err := s.users.Login(r.Context(), body.UserName, body.Password)
+	if err != nil {
+		respondWithServiceError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, Session{User: UserFromDB(user), Token: token})
+}
+
+func (s *Server) handleGetCurrentUser(w http.ResponseWriter, r *http.Request, user database.User) {
+	respondWithJSON(w, http.StatusOK, UserFromDB(user))
+}
+
+func (s *Server) handleGetFeeds(w http.ResponseWriter, r *http.Request) {
+	feeds, err := s.feeds.List(r.Context())
+	if err != nil {
+		respondWithServiceError(w, err)
+		return
+	}
+
+	response := []Feed{}
+	for _, feed := range feeds {
+		response = append(response, FeedFromDB(feed))
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// Creates a feed and follows it, like the addfeed command.
+func (s *Server) handleCreateFeed(w http.ResponseWriter, r *http.Request, user database.User) {
+	var body struct {
+		Name string `json:"name"`
+		Url  string `json:"url"`
+	}
+	err := decodeJSON(r, &body)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+
+	feed, err := s.feeds.Add(r.Context(), user, body.Name, body.Url)
+	if err != nil {
+		respondWithServiceError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, FeedFromDB(feed))
+}
+
+func (s *Server) handleGetFollows(w http.ResponseWriter, r *http.Request, user database.User) {
+	follows, err := s.feeds.Following(r.Context(), user)
+	if err != nil {
+		respondWithServiceError(w, err)
+		return
+	}
+
+	response := []FeedFollow{}
+	for _, follow := range follows {
+		response = append(response, FeedFollowFromDB(follow))
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func (s *Server) handleCreateFollow(w http.ResponseWriter, r *http.Request, user database.User) {
+	var body struct {
+		Url      string `json:"url"`
+		Category string `json:"category"`
+	}
+	err := decodeJSON(r, &body)
+	if err != nil || body.Url == "" {
+		respondWithError(w, http.StatusBadRequest, "url is required")
+		return
+	}
+
+	follow, err := s.feeds.Follow(r.Context(), user, body.Url, body.Category)
+	if err != nil {
+		respondWithServiceError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, FeedFollowFromDB(database.GetFeedFollowsByUserRow{
+		ID:        follow.ID,
+		CreatedAt: follow.CreatedAt,
+		UpdatedAt: follow.UpdatedAt,
+		UserID:    follow.UserID,
+		FeedID:    follow.FeedID,
+		Category:  follow.Category,
+		FeedName:  follow.FeedName,
+		FeedUrl:   body.Url,
+		UserName:  follow.UserName,
+	}))
+}
+
+func (s *Server) handleDeleteFollow(w http.ResponseWriter, r *http.Request, user database.User) {
+	feedID, err := uuid.Parse(r.PathValue("feedID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid feed id")
+		return
+	}
+
+	follows, err := s.feeds.Following(r.Context(), user)
+	if err != nil {
+		respondWithServiceError(w, err)
+		return
+	}
+
+	for _, follow := range follows {
+		if follow.FeedID == feedID {
+			err = s.feeds.Unfollow(r.Context(), user, follow.FeedUrl)
+			if err != nil {
+				respondWithServiceError(w, err)
+				return
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+
+	respondWithError(w, http.StatusNotFound, "feed not followed")
+}
+
+// Lists the posts of the followed feeds. Supports the same filters as the
+// browse command through query parameters.
+func (s *Server) handleGetPosts(w http.ResponseWriter, r *http.Request, user database.User) {
+	query := r.URL.Query()
+
+	opts := service.BrowseOptions{
+		Feed:     query.Get("feed"),
+		Category: query.Get("category"),
+		Unread:   query.Get("unread") == "true",
+		Starred:  query.Get("starred") == "true",
+		Limit:    int32(queryInt(query.Get("limit"), 20, 100)),
+		Offset:   int32(queryInt(query.Get("offset"), 0, -1)),
+	}
+
+	var err error
+	opts.Since, err = utils.ParseNullDate(query.Get("since"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid since date")
+		return
+	}
+	opts.Until, err = utils.ParseNullDate(query.Get("until"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid until date")
+		return
+	}
+
+	if cursor := query.Get("cursor"); cursor != "" {
+		after, err := service.ParseCursor(cursor)
+		if err != nil {
+			respondWithServiceError(w, err)
+			return
+		}
+		opts.Cursor = &after
+	}
+
+	posts, err := s.posts.Browse(r.Context(), user, opts)
+	if err != nil {
+		respondWithServiceError(w, err)
+		return
+	}
+
+	response := []Post{}
+	for _, post := range posts {
+		response = append(response, PostFromDB(post))
+	}
+
+	if len(posts) > 0 && len(posts) == int(opts.Limit) {
+		w.Header().Set("X-Next-Cursor", service.CursorAfter(posts[len(posts)-1]).String())
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// Serves the aggregated feed of a user, see PostService.AggregatedFeed. Feed
+// readers can't send the Authorization header so the user is part of the
+// path, like the optional category, and the feed token of the user is the
+// token query parameter.
+func (s *Server) handlePublishedFeed(format string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := s.users.GetByFeedToken(r.Context(), r.PathValue("userName"), r.URL.Query().Get("token"))
+		if err != nil {
+			respondWithServiceError(w, err)
+			return
+		}
+
+		limit := int32(queryInt(r.URL.Query().Get("limit"), 50, 500))
+
+		feed, err := s.posts.AggregatedFeed(r.Context(), user, r.PathValue("category"), limit)
+		if err != nil {
+			respondWithServiceError(w, err)
+			return
+		}
+
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+		// The token would leak to everyone the feed is forwarded to.
+		link := *r.URL
+		query := link.Query()
+		query.Del("token")
+		link.RawQuery = query.Encode()
+		feed.Link = fmt.Sprintf("%v://%v%v", scheme, r.Host, link.RequestURI())
+
+		contentType := "application/rss+xml; charset=utf-8"
+		if format == rss.FormatAtom {
+			contentType = "application/atom+xml; charset=utf-8"
+		}
+		w.Header().Set("Content-Type", contentType)
+
+		err = rss.Write(w, format, feed)
+		if err != nil {
+			log.Printf("Error writing feed: %v", err)
+		}
+	}
+}
+
+func (s *Server) handleSearchPosts(w http.ResponseWriter, r *http.Request, user database.User) {
+	limit := int32(queryInt(r.URL.Query().Get("limit"), 10, 100))
+
+	results, err := s.posts.Search(r.Context(), user, r.URL.Query().Get("q"), limit)
+	if err != nil {
+		respondWithServiceError(w, err)
+		return
+	}
+
+	response := []SearchResult{}
+	for _, result := range results {
+		response = append(response, SearchResult(result))
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func (s *Server) handleMarkRead(w http.ResponseWriter, r *http.Request, user database.User) {
+	s.updatePostState(w, r, user, s.posts.MarkRead)
+}
+
+func (s *Server) handleMarkUnread(w http.ResponseWriter, r *http.Request, user database.User) {
+	s.updatePostState(w, r, user, s.posts.MarkUnread)
+}
+
+func (s *Server) handleStar(w http.ResponseWriter, r *http.Request, user database.User) {
+	s.updatePostState(w, r, user, s.posts.Star)
+}
+
+func (s *Server) handleUnstar(w http.ResponseWriter, r *http.Request, user database.User) {
+	s.updatePostState(w, r, user, s.posts.Unstar)
+}
+
+type postStateUpdate func(ctx context.Context, user database.User, postID uuid.UUID) (database.Post, error)
+
+func (s *Server) updatePostState(w http.ResponseWriter, r *http.Request, user database.User, update postStateUpdate) {
+	postID, err := uuid.Parse(r.PathValue("postID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid post id")
+		return
+	}
+
+	_, err = update(r.Context(), user, postID)
+	if err != nil {
+		respondWithServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Parses a positive integer query parameter, max < 0 means no maximum.
+func queryInt(value string, defaultValue, max int) int {
+	i, err := strconv.Atoi(value)
+	if err != nil || i < 0 || (max >= 0 && i > max) {
+		return defaultValue
+	}
+
+	return i
+}
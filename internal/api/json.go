@@ -0,0 +1,54 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/Alb3G/gator/internal/service"
+)
+
+func respondWithJSON(w http.ResponseWriter, code int, payload any) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Error marshalling JSON: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(data)
+}
+
+func respondWithError(w http.ResponseWriter, code int, msg string) {
+	respondWithJSON(w, code, map[string]string{"error": msg})
+}
+
+// Maps the domain errors of the services to HTTP status codes, anything
+// unknown is a 500 and its details are only logged.
+func respondWithServiceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		respondWithError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrAlreadyExists):
+		respondWithError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, service.ErrInvalidInput):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrUnauthorized):
+		respondWithError(w, http.StatusUnauthorized, err.Error())
+	case errors.Is(err, service.ErrForbidden):
+		respondWithError(w, http.StatusForbidden, err.Error())
+	default:
+		log.Printf("Error handling request: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "internal server error")
+	}
+}
+
+func decodeJSON(r *http.Request, v any) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+
+	return decoder.Decode(v)
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"time"
+
+	"github.com/Alb3G/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+// JSON representations of the database models, nullable columns are
+// pointers so they are rendered as null instead of sql.Null* objects. The
+// CLI reuses them for its JSON output so both stay in sync.
+
+type User struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	UserName  string    `json:"user_name"`
+	IsAdmin   bool      `json:"is_admin"`
+}
+
+type Session struct {
+	User  User   `json:"user"`
+	Token string `json:"token"`
+}
+
+type Feed struct {
+	ID                  uuid.UUID  `json:"id"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+	Name                string     `json:"name"`
+	Url                 string     `json:"url"`
+	UserID              uuid.UUID  `json:"user_id"`
+	LastFetchedAt       *time.Time `json:"last_fetched_at"`
+	LastSuccessAt       *time.Time `json:"last_success_at"`
+	LastError           *string    `json:"last_error"`
+	ConsecutiveFailures int32      `json:"consecutive_failures"`
+}
+
+type FeedFollow struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	FeedID    uuid.UUID `json:"feed_id"`
+	FeedName  string    `json:"feed_name"`
+	FeedUrl   string    `json:"feed_url"`
+	Category  *string   `json:"category"`
+}
+
+type Post struct {
+	ID          uuid.UUID `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Title       string    `json:"title"`
+	Url         string    `json:"url"`
+	Description *string   `json:"description"`
+	PublishedAt time.Time `json:"published_at"`
+	FeedID      uuid.UUID `json:"feed_id"`
+}
+
+type SearchResult struct {
+	ID          uuid.UUID `json:"id"`
+	Title       string    `json:"title"`
+	Url         string    `json:"url"`
+	PublishedAt time.Time `json:"published_at"`
+	FeedName    string    `json:"feed_name"`
+	Rank        float32   `json:"rank"`
+	Snippet     string    `json:"snippet"`
+}
+
+func UserFromDB(user database.User) User {
+	return User{
+		ID:        user.ID,
+		CreatedAt: user.CreatedAt,
+		UpdatedAt: user.UpdatedAt,
+		UserName:  user.UserName,
+		IsAdmin:   user.IsAdmin,
+	}
+}
+
+func FeedFromDB(feed database.Feed) Feed {
+	f := Feed{
+		ID:                  feed.ID,
+		CreatedAt:           feed.CreatedAt,
+		UpdatedAt:           feed.UpdatedAt,
+		Name:                feed.Name,
+		Url:                 feed.Url,
+		UserID:              feed.UserID,
+		ConsecutiveFailures: feed.ConsecutiveFailures,
+	}
+
+	if feed.LastFetchedAt.Valid {
+		f.LastFetchedAt = &feed.LastFetchedAt.Time
+	}
+	if feed.LastSuccessAt.Valid {
+		f.LastSuccessAt = &feed.LastSuccessAt.Time
+	}
+	if feed.LastError.Valid {
+		f.LastError = &feed.LastError.String
+	}
+
+	return f
+}
+
+func FeedFollowFromDB(follow database.GetFeedFollowsByUserRow) FeedFollow {
+	f := FeedFollow{
+		ID:        follow.ID,
+		CreatedAt: follow.CreatedAt,
+		FeedID:    follow.FeedID,
+		FeedName:  follow.FeedName,
+		FeedUrl:   follow.FeedUrl,
+	}
+
+	if follow.Category.Valid {
+		f.Category = &follow.Category.String
+	}
+
+	return f
+}
+
+func PostFromDB(post database.Post) Post {
+	p := Post{
+		ID:          post.ID,
+		CreatedAt:   post.CreatedAt,
+		UpdatedAt:   post.UpdatedAt,
+		Title:       post.Title,
+		Url:         post.Url,
+		PublishedAt: post.PublishedAt,
+		FeedID:      post.FeedID,
+	}
+
+	if post.Description.Valid {
+		p.Description = &post.Description.String
+	}
+
+	return p
+}
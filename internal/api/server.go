@@ -0,0 +1,77 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/Alb3G/gator/internal/database"
+	rss "github.com/Alb3G/gator/internal/rss"
+	"github.com/Alb3G/gator/internal/service"
+)
+
+// Scheme of the Authorization header carrying the session token of the user
+// a request is made for.
+const authScheme = "Bearer "
+
+type Server struct {
+	users *service.UserService
+	feeds *service.FeedService
+	posts *service.PostService
+}
+
+func NewServer(users *service.UserService, feeds *service.FeedService, posts *service.PostService) *Server {
+	return &Server{users: users, feeds: feeds, posts: posts}
+}
+
+// Returns the handler serving the REST API.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /users", s.handleGetUsers)
+	mux.HandleFunc("POST /users", s.handleCreateUser)
+	mux.HandleFunc("GET /users/me", s.authenticated(s.handleGetCurrentUser))
+	mux.HandleFunc("POST /sessions", s.handleCreateSession)
+
+	mux.HandleFunc("GET /users/{userName}/feed.rss", s.handlePublishedFeed(rss.FormatRSS))
+	mux.HandleFunc("GET /users/{userName}/feed.atom", s.handlePublishedFeed(rss.FormatAtom))
+	mux.HandleFunc("GET /users/{userName}/categories/{category}/feed.rss", s.handlePublishedFeed(rss.FormatRSS))
+	mux.HandleFunc("GET /users/{userName}/categories/{category}/feed.atom", s.handlePublishedFeed(rss.FormatAtom))
+
+	mux.HandleFunc("GET /feeds", s.handleGetFeeds)
+	mux.HandleFunc("POST /feeds", s.authenticated(s.handleCreateFeed))
+
+	mux.HandleFunc("GET /follows", s.authenticated(s.handleGetFollows))
+	mux.HandleFunc("POST /follows", s.authenticated(s.handleCreateFollow))
+	mux.HandleFunc("DELETE /follows/{feedID}", s.authenticated(s.handleDeleteFollow))
+
+	mux.HandleFunc("GET /posts", s.authenticated(s.handleGetPosts))
+	mux.HandleFunc("GET /posts/search", s.authenticated(s.handleSearchPosts))
+	mux.HandleFunc("PUT /posts/{postID}/read", s.authenticated(s.handleMarkRead))
+	mux.HandleFunc("DELETE /posts/{postID}/read", s.authenticated(s.handleMarkUnread))
+	mux.HandleFunc("PUT /posts/{postID}/star", s.authenticated(s.handleStar))
+	mux.HandleFunc("DELETE /posts/{postID}/star", s.authenticated(s.handleUnstar))
+
+	return mux
+}
+
+type authedHandler func(http.ResponseWriter, *http.Request, database.User)
+
+// Same as MiddlewareLoggedIn for the CLI, resolves the user of the request
+// before calling the handler.
+func (s *Server) authenticated(handler authedHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), authScheme)
+		if !ok || token == "" {
+			respondWithError(w, http.StatusUnauthorized, "missing Authorization header")
+			return
+		}
+
+		user, err := s.users.GetBySession(r.Context(), token)
+		if err != nil {
+			respondWithServiceError(w, err)
+			return
+		}
+
+		handler(w, r, user)
+	}
+}
@@ -0,0 +1,432 @@
+// Package backup writes and reads gator archives: gzip compressed JSON lines,
+// a header first and then one line per row of every table. Tables are written
+// parents first, so the rows can be inserted back in the same order.
+package backup
+
+import (
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Alb3G/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+// Format and version written in the header of every archive. The version is
+// bumped whenever the rows change in a way older readers can't handle.
+const (
+	Format  = "gator-backup"
+	Version = 1
+)
+
+// SchemaVersion is the migration version of the database the archive was
+// written from, rows are only restored into a database at the same version.
+type Header struct {
+	Format        string    `json:"format"`
+	Version       int       `json:"version"`
+	SchemaVersion int64     `json:"schema_version"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// A line of the archive after the header.
+type line struct {
+	Table string `json:"table"`
+	Row   any    `json:"row"`
+}
+
+// Tables in the order they are written and read back, every table only
+// references the ones before it.
+var tables = []string{"users", "feeds", "feed_follows", "posts", "post_states"}
+
+// Sessions, feed and setup tokens aren't archived, users log in again and
+// create a new feed token after a restore.
+type User struct {
+	ID           uuid.UUID `json:"id"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	UserName     string    `json:"user_name"`
+	PasswordHash *string   `json:"password_hash"`
+	IsAdmin      bool      `json:"is_admin"`
+}
+
+type Feed struct {
+	ID                  uuid.UUID  `json:"id"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+	Name                string     `json:"name"`
+	Url                 string     `json:"url"`
+	UserID              uuid.UUID  `json:"user_id"`
+	LastFetchedAt       *time.Time `json:"last_fetched_at"`
+	Etag                *string    `json:"etag"`
+	LastModified        *string    `json:"last_modified"`
+	LastError           *string    `json:"last_error"`
+	ConsecutiveFailures int32      `json:"consecutive_failures"`
+	LastSuccessAt       *time.Time `json:"last_success_at"`
+	NextFetchAt         *time.Time `json:"next_fetch_at"`
+}
+
+type FeedFollow struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	UserID    uuid.UUID `json:"user_id"`
+	FeedID    uuid.UUID `json:"feed_id"`
+	Category  *string   `json:"category"`
+}
+
+type Post struct {
+	ID          uuid.UUID `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Title       string    `json:"title"`
+	Url         string    `json:"url"`
+	Description *string   `json:"description"`
+	PublishedAt time.Time `json:"published_at"`
+	FeedID      uuid.UUID `json:"feed_id"`
+	Guid        *string   `json:"guid"`
+}
+
+type PostState struct {
+	UserID    uuid.UUID  `json:"user_id"`
+	PostID    uuid.UUID  `json:"post_id"`
+	ReadAt    *time.Time `json:"read_at"`
+	StarredAt *time.Time `json:"starred_at"`
+}
+
+// Number of rows of every table in an archive.
+type Counts struct {
+	Users       int
+	Feeds       int
+	FeedFollows int
+	Posts       int
+	PostStates  int
+}
+
+func (c Counts) String() string {
+	return fmt.Sprintf("%v users, %v feeds, %v follows, %v posts, %v post states", c.Users, c.Feeds, c.FeedFollows, c.Posts, c.PostStates)
+}
+
+// Rows read per query, so a whole table is never held in memory.
+const batchSize = 500
+
+// Writes an archive of every row readable through q, at the given schema
+// version. Run it in a transaction to get a consistent snapshot.
+func Write(ctx context.Context, q database.Querier, w io.Writer, schemaVersion int64) (counts Counts, err error) {
+	gz := gzip.NewWriter(w)
+	defer func() {
+		err = errors.Join(err, gz.Close())
+	}()
+	enc := json.NewEncoder(gz)
+
+	err = enc.Encode(Header{Format: Format, Version: Version, SchemaVersion: schemaVersion, CreatedAt: time.Now().UTC()})
+	if err != nil {
+		return counts, err
+	}
+
+	counts.Users, err = writeRows(enc, "users",
+		func(after database.User) ([]database.User, error) {
+			return q.GetUsersAfter(ctx, database.GetUsersAfterParams{AfterID: after.ID, Limit: batchSize})
+		},
+		func(u database.User) any {
+			return User{
+				ID:           u.ID,
+				CreatedAt:    u.CreatedAt,
+				UpdatedAt:    u.UpdatedAt,
+				UserName:     u.UserName,
+				PasswordHash: fromNullString(u.PasswordHash),
+				IsAdmin:      u.IsAdmin,
+			}
+		})
+	if err != nil {
+		return counts, err
+	}
+
+	counts.Feeds, err = writeRows(enc, "feeds",
+		func(after database.Feed) ([]database.Feed, error) {
+			return q.GetFeedsAfter(ctx, database.GetFeedsAfterParams{AfterID: after.ID, Limit: batchSize})
+		},
+		func(f database.Feed) any {
+			return Feed{
+				ID:                  f.ID,
+				CreatedAt:           f.CreatedAt,
+				UpdatedAt:           f.UpdatedAt,
+				Name:                f.Name,
+				Url:                 f.Url,
+				UserID:              f.UserID,
+				LastFetchedAt:       fromNullTime(f.LastFetchedAt),
+				Etag:                fromNullString(f.Etag),
+				LastModified:        fromNullString(f.LastModified),
+				LastError:           fromNullString(f.LastError),
+				ConsecutiveFailures: f.ConsecutiveFailures,
+				LastSuccessAt:       fromNullTime(f.LastSuccessAt),
+				NextFetchAt:         fromNullTime(f.NextFetchAt),
+			}
+		})
+	if err != nil {
+		return counts, err
+	}
+
+	counts.FeedFollows, err = writeRows(enc, "feed_follows",
+		func(after database.FeedFollow) ([]database.FeedFollow, error) {
+			return q.GetFeedFollowsAfter(ctx, database.GetFeedFollowsAfterParams{AfterID: after.ID, Limit: batchSize})
+		},
+		func(ff database.FeedFollow) any {
+			return FeedFollow{
+				ID:        ff.ID,
+				CreatedAt: ff.CreatedAt,
+				UpdatedAt: ff.UpdatedAt,
+				UserID:    ff.UserID,
+				FeedID:    ff.FeedID,
+				Category:  fromNullString(ff.Category),
+			}
+		})
+	if err != nil {
+		return counts, err
+	}
+
+	counts.Posts, err = writeRows(enc, "posts",
+		func(after database.Post) ([]database.Post, error) {
+			return q.GetPostsAfter(ctx, database.GetPostsAfterParams{AfterID: after.ID, Limit: batchSize})
+		},
+		func(p database.Post) any {
+			return Post{
+				ID:          p.ID,
+				CreatedAt:   p.CreatedAt,
+				UpdatedAt:   p.UpdatedAt,
+				Title:       p.Title,
+				Url:         p.Url,
+				Description: fromNullString(p.Description),
+				PublishedAt: p.PublishedAt,
+				FeedID:      p.FeedID,
+				Guid:        fromNullString(p.Guid),
+			}
+		})
+	if err != nil {
+		return counts, err
+	}
+
+	counts.PostStates, err = writeRows(enc, "post_states",
+		func(after database.PostState) ([]database.PostState, error) {
+			return q.GetPostStatesAfter(ctx, database.GetPostStatesAfterParams{AfterUserID: after.UserID, AfterPostID: after.PostID, Limit: batchSize})
+		},
+		func(ps database.PostState) any {
+			return PostState{
+				UserID:    ps.UserID,
+				PostID:    ps.PostID,
+				ReadAt:    fromNullTime(ps.ReadAt),
+				StarredAt: fromNullTime(ps.StarredAt),
+			}
+		})
+
+	return counts, err
+}
+
+// Writes a table page by page and returns its number of rows. page returns
+// the rows after the given one, the zero row for the first page.
+func writeRows[T any](enc *json.Encoder, table string, page func(after T) ([]T, error), row func(T) any) (int, error) {
+	var after T
+	n := 0
+
+	for {
+		rows, err := page(after)
+		if err != nil {
+			return n, err
+		}
+
+		for _, r := range rows {
+			err = enc.Encode(line{Table: table, Row: row(r)})
+			if err != nil {
+				return n, err
+			}
+			n++
+		}
+
+		if len(rows) < batchSize {
+			return n, nil
+		}
+		after = rows[len(rows)-1]
+	}
+}
+
+func fromNullString(s sql.NullString) *string {
+	if !s.Valid {
+		return nil
+	}
+	return &s.String
+}
+
+func fromNullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+// Inserts every row of an archive through q into a database at the given
+// schema version. Run it in a transaction so a broken archive leaves nothing
+// behind.
+func Read(ctx context.Context, q database.Querier, r io.Reader, schemaVersion int64) (Counts, error) {
+	var counts Counts
+
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return counts, fmt.Errorf("not a gator backup: %w", err)
+	}
+	defer gz.Close()
+	dec := json.NewDecoder(gz)
+
+	var header Header
+	err = dec.Decode(&header)
+	if err != nil || header.Format != Format {
+		return counts, errors.New("not a gator backup: missing header")
+	}
+	if header.Version < 1 || header.Version > Version {
+		return counts, fmt.Errorf("unsupported backup version %v, this gator reads versions 1 to %v", header.Version, Version)
+	}
+	if header.SchemaVersion != schemaVersion {
+		return counts, fmt.Errorf("the backup was written at schema version %v but the database is at version %v, restore it with the gator release that wrote it", header.SchemaVersion, schemaVersion)
+	}
+
+	table := 0
+	for n := 2; dec.More(); n++ {
+		var l struct {
+			Table string          `json:"table"`
+			Row   json.RawMessage `json:"row"`
+		}
+		err = dec.Decode(&l)
+		if err != nil {
+			return counts, fmt.Errorf("line %v: %w", n, err)
+		}
+
+		for table < len(tables) && tables[table] != l.Table {
+			table++
+		}
+		if table == len(tables) {
+			return counts, fmt.Errorf("line %v: unknown or out of order table %q", n, l.Table)
+		}
+
+		err = restoreRow(ctx, q, l.Table, l.Row, &counts)
+		if err != nil {
+			return counts, fmt.Errorf("line %v: %v: %w", n, l.Table, err)
+		}
+	}
+
+	// More stops on read errors too, a truncated archive must not pass for a
+	// shorter one.
+	token, err := dec.Token()
+	if err == nil {
+		err = fmt.Errorf("unexpected %v", token)
+	}
+	if err != io.EOF {
+		return counts, fmt.Errorf("reading the backup: %w", err)
+	}
+
+	return counts, nil
+}
+
+func restoreRow(ctx context.Context, q database.Querier, table string, row json.RawMessage, counts *Counts) error {
+	switch table {
+	case "users":
+		var u User
+		err := json.Unmarshal(row, &u)
+		if err != nil {
+			return err
+		}
+		counts.Users++
+		return q.RestoreUser(ctx, database.RestoreUserParams{
+			ID:           u.ID,
+			CreatedAt:    u.CreatedAt,
+			UpdatedAt:    u.UpdatedAt,
+			UserName:     u.UserName,
+			PasswordHash: toNullString(u.PasswordHash),
+			IsAdmin:      u.IsAdmin,
+		})
+	case "feeds":
+		var f Feed
+		err := json.Unmarshal(row, &f)
+		if err != nil {
+			return err
+		}
+		counts.Feeds++
+		return q.RestoreFeed(ctx, database.RestoreFeedParams{
+			ID:                  f.ID,
+			CreatedAt:           f.CreatedAt,
+			UpdatedAt:           f.UpdatedAt,
+			Name:                f.Name,
+			Url:                 f.Url,
+			UserID:              f.UserID,
+			LastFetchedAt:       toNullTime(f.LastFetchedAt),
+			Etag:                toNullString(f.Etag),
+			LastModified:        toNullString(f.LastModified),
+			LastError:           toNullString(f.LastError),
+			ConsecutiveFailures: f.ConsecutiveFailures,
+			LastSuccessAt:       toNullTime(f.LastSuccessAt),
+			NextFetchAt:         toNullTime(f.NextFetchAt),
+		})
+	case "feed_follows":
+		var ff FeedFollow
+		err := json.Unmarshal(row, &ff)
+		if err != nil {
+			return err
+		}
+		counts.FeedFollows++
+		return q.RestoreFeedFollow(ctx, database.RestoreFeedFollowParams{
+			ID:        ff.ID,
+			CreatedAt: ff.CreatedAt,
+			UpdatedAt: ff.UpdatedAt,
+			UserID:    ff.UserID,
+			FeedID:    ff.FeedID,
+			Category:  toNullString(ff.Category),
+		})
+	case "posts":
+		var p Post
+		err := json.Unmarshal(row, &p)
+		if err != nil {
+			return err
+		}
+		counts.Posts++
+		return q.RestorePost(ctx, database.RestorePostParams{
+			ID:          p.ID,
+			CreatedAt:   p.CreatedAt,
+			UpdatedAt:   p.UpdatedAt,
+			Title:       p.Title,
+			Url:         p.Url,
+			Description: toNullString(p.Description),
+			PublishedAt: p.PublishedAt,
+			FeedID:      p.FeedID,
+			Guid:        toNullString(p.Guid),
+		})
+	default:
+		var ps PostState
+		err := json.Unmarshal(row, &ps)
+		if err != nil {
+			return err
+		}
+		counts.PostStates++
+		return q.RestorePostState(ctx, database.RestorePostStateParams{
+			UserID:    ps.UserID,
+			PostID:    ps.PostID,
+			ReadAt:    toNullTime(ps.ReadAt),
+			StarredAt: toNullTime(ps.StarredAt),
+		})
+	}
+}
+
+func toNullString(s *string) sql.NullString {
+	if s == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: *s, Valid: true}
+}
+
+func toNullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: *t, Valid: true}
+}
@@ -2,51 +2,38 @@ package internal
 
 import (
 	"context"
-	"database/sql"
 	"errors"
 	"fmt"
 	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
+	"github.com/Alb3G/gator/internal/api"
+	"github.com/Alb3G/gator/internal/backup"
 	conf "github.com/Alb3G/gator/internal/config"
 	"github.com/Alb3G/gator/internal/database"
+	"github.com/Alb3G/gator/internal/opml"
+	"github.com/Alb3G/gator/internal/output"
 	rss "github.com/Alb3G/gator/internal/rss"
+	"github.com/Alb3G/gator/internal/service"
 	utils "github.com/Alb3G/gator/internal/utils"
 	uuid "github.com/google/uuid"
 )
 
-type Command struct {
-	Name string
-	Args []string
-}
-
-type Commands struct {
-	AvailableCommands map[string]func(*conf.State, Command) error
-}
-
-// This method runs a given command with the provided state if it exists.
-func (c *Commands) Run(state *conf.State, cmd Command) error {
-	f, ok := c.AvailableCommands[cmd.Name]
-	if !ok {
-		return errors.New("command not found")
-	}
-
-	return f(state, cmd)
-}
-
-// This method registers a new handler function for a command name.
-func (c *Commands) Register(name string, f func(*conf.State, Command) error) {
-	c.AvailableCommands[name] = f
-}
-
-func MiddlewareLoggedIn(handler func(s *conf.State, c Command, user database.User) error) func(s *conf.State, c Command) error {
-	return func(s *conf.State, c Command) error {
-		user, err := s.Queries.GetUserByName(context.Background(), s.Config.CurrentUserName)
+func MiddlewareLoggedIn(handler func(ctx context.Context, s *conf.State, c Command, user database.User) error) func(ctx context.Context, s *conf.State, c Command) error {
+	return func(ctx context.Context, s *conf.State, c Command) error {
+		user, err := s.Users.GetBySession(ctx, s.Config.SessionToken)
+		if errors.Is(err, service.ErrUnauthorized) {
+			return fmt.Errorf("%w, run `gator login <username>`", err)
+		}
 		if err != nil {
 			return err
 		}
 
-		err = handler(s, c, user)
+		err = handler(ctx, s, c, user)
 		if err != nil {
 			return err
 		}
@@ -55,258 +42,647 @@ func MiddlewareLoggedIn(handler func(s *conf.State, c Command, user database.Use
 	}
 }
 
-func LoginHandler(s *conf.State, cmd Command) error {
-	if len(cmd.Args) < 2 {
-		return errors.New("missing username argument")
-	}
-
+func LoginHandler(ctx context.Context, s *conf.State, cmd Command) error {
 	userName := cmd.Args[1]
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	password, err := readPassword(ctx, "Password: ")
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	_, err := s.Queries.GetUserByName(ctx, userName)
+	user, token, err := s.Users.Login(ctx, userName, password)
 	if err != nil {
 		return err
 	}
 
-	s.Config.SetUser(userName)
+	s.Config.SetSession(user.UserName, token)
+
+	fmt.Println("New User has been set.")
 
 	return nil
 }
 
-func RegisterHandler(s *conf.State, c Command) error {
-	// Add a util function in the future to validate correct userNames
-	if len(c.Args) != 2 {
-		return errors.New("no user name provided")
-	}
-
+func RegisterHandler(ctx context.Context, s *conf.State, c Command) error {
 	userName := c.Args[1]
-	uuid := uuid.New()
-	dbArgs := database.CreateUserParams{
-		ID:        uuid,
-		CreatedAt: utils.Now(),
-		UpdatedAt: utils.Now(),
-		UserName:  userName,
+
+	password, err := readNewPassword(ctx, "Password: ")
+	if err != nil {
+		return err
 	}
 
 	// Generate context with Timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	userFromDb, err := s.Queries.GetUserByName(ctx, userName)
-	if err != nil && err != sql.ErrNoRows {
+	user, err := s.Users.Register(ctx, userName, password)
+	if err != nil {
 		return err
 	}
 
-	if userFromDb.UserName != "" {
-		return errors.New("user_name already exists in db")
-	}
+	fmt.Println("User created successfully")
+	fmt.Printf("%v (%v)\n", user.UserName, user.ID)
 
-	user, err := s.Queries.CreateUser(ctx, dbArgs)
+	_, token, err := s.Users.Login(ctx, userName, password)
 	if err != nil {
 		return err
 	}
 
-	fmt.Println("User created successfully")
-	fmt.Println(user)
+	s.Config.SetSession(user.UserName, token)
 
-	s.Config.SetUser(userName)
+	fmt.Println("New User has been set.")
 
 	return nil
 }
 
-func ResetHandler(s *conf.State, c Command) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+// Changes the password of the current user, logging out its other sessions.
+func Passwd(ctx context.Context, s *conf.State, c Command, user database.User) error {
+	current, err := readPassword(ctx, "Current password: ")
+	if err != nil {
+		return err
+	}
+
+	password, err := readNewPassword(ctx, "New password: ")
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	err := s.Queries.Reset(ctx)
+	token, err := s.Users.ChangePassword(ctx, user, current, password)
 	if err != nil {
 		return err
 	}
 
+	s.Config.SetSession(user.UserName, token)
+
+	fmt.Println("Password changed, the other sessions have been logged out.")
+
 	return nil
 }
 
-func Users(s *conf.State, c Command) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+// Sets the password of a user. An admin can set anyone's, an admin without a
+// password sets their first one with the setup token printed by migrate up.
+func SetPassword(ctx context.Context, s *conf.State, c Command) error {
+	token := c.String("token")
+	if token == "" {
+		return MiddlewareLoggedIn(setPasswordAsAdmin)(ctx, s, c)
+	}
+
+	userName := c.Args[1]
 
-	users, err := s.Queries.GetUsers(ctx)
+	password, err := readNewPassword(ctx, "New password: ")
 	if err != nil {
 		return err
 	}
 
-	for _, user := range users {
-		if user.UserName == s.Config.CurrentUserName {
-			fmt.Printf("* %v (current)", s.Config.CurrentUserName)
-		} else {
-			fmt.Print("* ")
-			fmt.Println(user.UserName)
-		}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
 
+	err = s.Users.SetFirstPassword(ctx, userName, token, password)
+	if err != nil {
+		return err
 	}
 
+	fmt.Printf("Password of %v set, run `gator login %v` to log in.\n", userName, userName)
+
 	return nil
 }
 
-func Agg(s *conf.State, c Command) error {
-	if len(c.Args) < 2 {
-		return errors.New("missing time_between_reqs arg")
+func setPasswordAsAdmin(ctx context.Context, s *conf.State, c Command, user database.User) error {
+	userName := c.Args[1]
+
+	password, err := readNewPassword(ctx, "New password: ")
+	if err != nil {
+		return err
 	}
-	time_between_reqs, err := time.ParseDuration(c.Args[1])
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	err = s.Users.SetPassword(ctx, user, userName, password)
 	if err != nil {
 		return err
 	}
 
-	fmt.Printf("Collecting feeds every %v\n", time_between_reqs)
+	fmt.Printf("Password of %v set, their sessions have been logged out.\n", userName)
 
-	ticker := time.NewTicker(time_between_reqs)
+	return nil
+}
 
-	for ; ; <-ticker.C {
-		scrapeFeeds(s)
+// Deletes the data selected by the flags, everything by default, after
+// confirming it and writing a snapshot that backup archives can restore.
+func ResetHandler(ctx context.Context, s *conf.State, c Command, user database.User) error {
+	scope := service.ResetScope{
+		Posts: c.Bool("posts"),
+		Feeds: c.Bool("feeds"),
+		User:  c.String("user"),
 	}
-}
 
-func AddFeed(s *conf.State, c Command, user database.User) error {
-	if len(c.Args) < 3 {
-		return errors.New("missing required args feed_name or url")
+	question := "Delete every user, feed and post?"
+	scopes := 0
+	if scope.Posts {
+		question = "Delete every post?"
+		scopes++
+	}
+	if scope.Feeds {
+		question = "Delete every feed, with their follows and posts?"
+		scopes++
+	}
+	if scope.User != "" {
+		question = fmt.Sprintf("Delete user %v, with their feeds, follows and post states?", scope.User)
+		scopes++
+	}
+	if scopes > 1 {
+		return errors.New("only one of --posts, --feeds and --user can be given")
 	}
-	name := c.Args[1]
-	url := c.Args[2]
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	err := service.RequireAdmin(user)
+	if err != nil {
+		return err
+	}
 
-	feedArgs := database.CreateFeedParams{
-		ID:        uuid.New(),
-		CreatedAt: time.Now().UTC(),
-		UpdatedAt: time.Now().UTC(),
-		Name:      name,
-		Url:       url,
-		UserID:    user.ID,
+	if !c.Bool("yes") {
+		ok, err := confirm(question)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Println("Reset cancelled.")
+			return nil
+		}
 	}
 
-	feed, err := s.Queries.CreateFeed(ctx, feedArgs)
+	path, err := snapshotPath("reset")
 	if err != nil {
 		return err
 	}
 
-	feed_follow_Args := database.CreateFeedFollowParams{
-		ID:        uuid.New(),
-		CreatedAt: time.Now().UTC(),
-		UpdatedAt: time.Now().UTC(),
-		UserID:    user.ID,
-		FeedID:    feed.ID,
-	}
+	// Archiving a large database takes longer than a single query.
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Minute)
+	defer cancel()
 
-	_, err = s.Queries.CreateFeedFollow(ctx, feed_follow_Args)
+	var counts backup.Counts
+	err = s.Users.Reset(ctx, user, scope, func(q database.Querier) error {
+		counts, err = writeArchive(ctx, q, path, s.Migrations.Latest())
+		return err
+	})
 	if err != nil {
 		return err
 	}
 
-	fmt.Println(feed)
+	fmt.Printf("Snapshot of %v written to %v\n", counts, path)
+	fmt.Println("Reset done.")
 
 	return nil
 }
 
-func FeedsHandler(s *conf.State, c Command, user database.User) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func BackupHandler(ctx context.Context, s *conf.State, c Command, user database.User) error {
+	path := c.Args[1]
+
+	// Archiving a large database takes longer than a single query.
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Minute)
 	defer cancel()
 
-	feeds, err := s.Queries.GetFeeds(ctx)
+	var counts backup.Counts
+	err := s.Users.Backup(ctx, user, func(q database.Querier) error {
+		var err error
+		counts, err = writeArchive(ctx, q, path, s.Migrations.Latest())
+		return err
+	})
 	if err != nil {
 		return err
 	}
 
-	for _, feed := range feeds {
-		fmt.Println(user.UserName)
-		fmt.Println(feed)
+	fmt.Printf("Backup of %v written to %v\n", counts, path)
+
+	return nil
+}
+
+// Replaces the whole database with an archive, after confirming it and
+// writing a snapshot of the data it replaces.
+func RestoreHandler(ctx context.Context, s *conf.State, c Command, user database.User) error {
+	path := c.Args[1]
+
+	err := service.RequireAdmin(user)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if !c.Bool("yes") {
+		ok, err := confirm(fmt.Sprintf("Replace every user, feed and post with the content of %v?", path))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Println("Restore cancelled.")
+			return nil
+		}
 	}
 
+	snapshot, err := snapshotPath("restore")
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Minute)
+	defer cancel()
+
+	schemaVersion := s.Migrations.Latest()
+
+	var snapshotCounts, counts backup.Counts
+	err = s.Users.Restore(ctx, user,
+		func(q database.Querier) error {
+			snapshotCounts, err = writeArchive(ctx, q, snapshot, schemaVersion)
+			return err
+		},
+		func(q database.Querier) error {
+			counts, err = backup.Read(ctx, q, file, schemaVersion)
+			return err
+		})
+	if err != nil {
+		return fmt.Errorf("restoring %v: %w", path, err)
+	}
+
+	fmt.Printf("Snapshot of %v written to %v\n", snapshotCounts, snapshot)
+	fmt.Printf("Restored %v from %v\n", counts, path)
+	fmt.Println("Sessions aren't backed up, run `gator login <username>` to log in.")
+
 	return nil
 }
 
-func scrapeFeeds(s *conf.State) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+// Path of a new snapshot taken before the operation named op deletes data.
+// The time goes down to the nanosecond, a script running two resets in the
+// same second must not replace the first snapshot with the second.
+func snapshotPath(op string) (string, error) {
+	dir, err := conf.SnapshotDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, op+"-"+time.Now().UTC().Format("20060102-150405.000000000")+".jsonl.gz"), nil
+}
+
+// Writes a backup archive only readable by the user, it holds the password
+// hashes. The archive is written to a temporary file renamed once complete, so
+// an existing file at path is only replaced by a whole archive.
+func writeArchive(ctx context.Context, q database.Querier, path string, schemaVersion int64) (backup.Counts, error) {
+	err := os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return backup.Counts{}, err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return backup.Counts{}, err
+	}
+
+	counts, err := backup.Write(ctx, q, f, schemaVersion)
+	err = errors.Join(err, f.Close())
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return backup.Counts{}, err
+	}
+
+	return counts, nil
+}
+
+// JSON record of the users command, the API user and whether it is the
+// logged in one.
+type userRecord struct {
+	api.User
+	Current bool `json:"current"`
+}
+
+func Users(ctx context.Context, s *conf.State, c Command) error {
+	format := output.FromFlags(c.Flags)
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	lastFeedFetched, err := s.Queries.GetNextFeedToFetch(ctx)
+	users, err := s.Users.List(ctx)
 	if err != nil {
 		return err
 	}
 
-	feedFetchedParams := database.MarkFeedFetchedParams{
-		LastFetchedAt: sql.NullTime{
-			Time:  time.Now().UTC(),
-			Valid: true,
+	current := func(user database.User) bool {
+		return user.UserName == s.Config.CurrentUserName
+	}
+
+	return output.Render(os.Stdout, format, users, output.View[database.User]{
+		Columns: []output.Column[database.User]{
+			{Header: "NAME", Value: func(u database.User) any { return u.UserName }},
+			{Header: "CURRENT", Value: func(u database.User) any {
+				if current(u) {
+					return "*"
+				}
+				return ""
+			}},
+			{Header: "ADMIN", Value: func(u database.User) any {
+				if u.IsAdmin {
+					return "*"
+				}
+				return ""
+			}},
+			{Header: "CREATED", Value: func(u database.User) any { return u.CreatedAt }},
+		},
+		Record: func(u database.User) any {
+			return userRecord{User: api.UserFromDB(u), Current: current(u)}
 		},
-		UpdatedAt: time.Now().UTC(),
-		ID:        lastFeedFetched.ID,
+	})
+}
+
+func Agg(ctx context.Context, s *conf.State, c Command) error {
+	time_between_reqs, err := time.ParseDuration(c.Args[1])
+	if err != nil {
+		return err
+	}
+
+	concurrency := utils.ParseIntArg(c.Args, 2, 5)
+	batchSize := utils.ParseIntArg(c.Args, 3, concurrency)
+
+	fmt.Printf("Collecting %v feeds every %v with %v workers\n", batchSize, time_between_reqs, concurrency)
+
+	ticker := time.NewTicker(time_between_reqs)
+	defer ticker.Stop()
+
+	for {
+		// Once ctx is cancelled Scrape finishes the feeds in flight and
+		// returns, so no feed is left half ingested.
+		result, err := s.Feeds.Scrape(ctx, concurrency, batchSize)
+		switch {
+		case err == nil:
+			fmt.Printf("Scraped %v\n", result)
+		case ctx.Err() == nil:
+			log.Printf("Error claiming feeds to fetch: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			fmt.Println("Stopped collecting feeds")
+			return nil
+		case <-ticker.C:
+		}
 	}
+}
 
-	err = s.Queries.MarkFeedFetched(ctx, feedFetchedParams)
+func FeedHealth(ctx context.Context, s *conf.State, c Command) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	feeds, err := s.Feeds.Failing(ctx)
 	if err != nil {
 		return err
 	}
 
-	rssStruct, err := rss.FetchFeed(ctx, lastFeedFetched.Url)
+	if len(feeds) == 0 {
+		fmt.Println("All feeds are healthy")
+		return nil
+	}
+
+	for _, feed := range feeds {
+		fmt.Printf("* %v (%v)\n", feed.Name, feed.Url)
+		fmt.Printf("  consecutive failures: %v\n", feed.ConsecutiveFailures)
+		fmt.Printf("  last success:         %v\n", utils.FormatNullTime(feed.LastSuccessAt, "never"))
+		fmt.Printf("  next fetch:           %v\n", utils.FormatNullTime(feed.NextFetchAt, "now"))
+		fmt.Printf("  last error:           %v\n", feed.LastError.String)
+	}
+
+	return nil
+}
+
+func Serve(ctx context.Context, s *conf.State, c Command) error {
+	addr := "localhost:8080"
+	if len(c.Args) > 1 {
+		addr = c.Args[1]
+	}
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           api.NewServer(s.Users, s.Feeds, s.Posts).Handler(),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	fmt.Printf("Serving the gator API on %v\n", addr)
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	// Let the requests in flight finish before closing the database.
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+
+	err := server.Shutdown(shutdownCtx)
 	if err != nil {
 		return err
 	}
 
-	for _, item := range rssStruct.Channel.Item {
-		pubDate, err := utils.ParsePublishedDate(item.PubDate)
+	fmt.Println("Stopped serving the gator API")
+
+	return nil
+}
+
+func Migrate(ctx context.Context, s *conf.State, c Command) error {
+	// Migrations may rewrite whole tables, don't cut them short.
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	switch c.Args[1] {
+	case "up":
+		applied, err := s.Migrations.Up(ctx)
+		for _, migration := range applied {
+			fmt.Printf("Applied %v\n", migration.Name)
+		}
+		if err != nil {
+			return err
+		}
+
+		if len(applied) == 0 {
+			fmt.Printf("Database is up to date at version %v\n", s.Migrations.Latest())
+		}
+
+		// Admins from before passwords existed can't log in to set one.
+		tokens, err := s.Users.NewSetupTokens(ctx)
+		if err != nil {
+			return err
+		}
+		for _, token := range tokens {
+			fmt.Printf("Admin %v has no password, set it with `gator setpassword --token %v %v`\n", token.UserName, token.Token, token.UserName)
+		}
+
+		return nil
+	case "down":
+		migration, ok, err := s.Migrations.Down(ctx)
 		if err != nil {
-			log.Printf("Error parsing date: %v", err)
 			return err
 		}
 
-		postParams := database.CreatePostParams{
-			ID:        uuid.New(),
-			CreatedAt: time.Now().UTC(),
-			UpdatedAt: time.Now().UTC(),
-			Title:     item.Title,
-			Url:       item.Link,
-			Description: sql.NullString{
-				String: item.Description,
-				Valid:  true,
-			},
-			PublishedAt: pubDate,
-			FeedID:      lastFeedFetched.ID,
+		if !ok {
+			fmt.Println("No migration to roll back")
+			return nil
 		}
-		_, err = s.Queries.CreatePost(ctx, postParams)
+
+		fmt.Printf("Rolled back %v\n", migration.Name)
+
+		return nil
+	case "status":
+		statuses, err := s.Migrations.Status(ctx)
 		if err != nil {
-			log.Printf("Error creating post: %v", err)
 			return err
 		}
+
+		for _, status := range statuses {
+			if status.Applied {
+				fmt.Printf("* %v applied %v\n", status.Migration.Name, status.AppliedAt.Format(time.RFC1123))
+			} else {
+				fmt.Printf("* %v pending\n", status.Migration.Name)
+			}
+		}
+
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate subcommand %q, expected up, down or status", c.Args[1])
+	}
+}
+
+func AddFeed(ctx context.Context, s *conf.State, c Command, user database.User) error {
+	name := c.Args[1]
+	url := c.Args[2]
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	feed, err := s.Feeds.Add(ctx, user, name, url)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(feed)
+
+	return nil
+}
+
+func Import(ctx context.Context, s *conf.State, c Command, user database.User) error {
+	file, err := os.Open(c.Args[1])
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	subscriptions, err := opml.Read(file)
+	if err != nil {
+		return fmt.Errorf("invalid opml file: %w", err)
 	}
 
+	// Importing hundreds of feeds takes longer than a single query.
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	result, err := s.Feeds.Import(ctx, user, subscriptions)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Imported %v feeds: %v created, %v followed, %v already followed\n",
+		result.Total, result.Created, result.Followed, result.AlreadyFollowed)
+
 	return nil
 }
 
-func Follow(s *conf.State, c Command, user database.User) error {
+func Export(ctx context.Context, s *conf.State, c Command, user database.User) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	subscriptions, err := s.Feeds.Export(ctx, user)
+	if err != nil {
+		return err
+	}
+
+	title := fmt.Sprintf("%v subscriptions in gator", user.UserName)
+
 	if len(c.Args) < 2 {
-		return errors.New("missing url arg")
+		return opml.Write(os.Stdout, title, subscriptions)
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	file, err := os.Create(c.Args[1])
+	if err != nil {
+		return err
+	}
+
+	err = opml.Write(file, title, subscriptions)
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	err = file.Close()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Exported %v feeds to %v\n", len(subscriptions), c.Args[1])
+
+	return nil
+}
+
+func FeedsHandler(ctx context.Context, s *conf.State, c Command, user database.User) error {
+	format := output.FromFlags(c.Flags)
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	feed, err := s.Queries.GetFeedByURL(ctx, c.Args[1])
+	feeds, err := s.Feeds.List(ctx)
 	if err != nil {
 		return err
 	}
 
-	feed_follow_Args := database.CreateFeedFollowParams{
-		ID:        uuid.New(),
-		CreatedAt: time.Now().UTC(),
-		UpdatedAt: time.Now().UTC(),
-		UserID:    user.ID,
-		FeedID:    feed.ID,
+	users, err := s.Users.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	owners := map[uuid.UUID]string{}
+	for _, u := range users {
+		owners[u.ID] = u.UserName
 	}
 
-	inserted_feed_follow, err := s.Queries.CreateFeedFollow(ctx, feed_follow_Args)
+	return output.Render(os.Stdout, format, feeds, output.View[database.Feed]{
+		Columns: []output.Column[database.Feed]{
+			{Header: "NAME", Value: func(f database.Feed) any { return f.Name }},
+			{Header: "URL", Value: func(f database.Feed) any { return f.Url }},
+			{Header: "OWNER", Value: func(f database.Feed) any { return owners[f.UserID] }},
+			{Header: "LAST FETCHED", Value: func(f database.Feed) any { return api.FeedFromDB(f).LastFetchedAt }},
+			{Header: "FAILURES", Value: func(f database.Feed) any { return f.ConsecutiveFailures }},
+		},
+		Record: func(f database.Feed) any { return api.FeedFromDB(f) },
+	})
+}
+
+func Follow(ctx context.Context, s *conf.State, c Command, user database.User) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	inserted_feed_follow, err := s.Feeds.Follow(ctx, user, c.Args[1], "")
 	if err != nil {
 		return err
 	}
@@ -316,65 +692,222 @@ func Follow(s *conf.State, c Command, user database.User) error {
 	return nil
 }
 
-func Following(s *conf.State, c Command, user database.User) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func Following(ctx context.Context, s *conf.State, c Command, user database.User) error {
+	format := output.FromFlags(c.Flags)
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	feedFollowsByUser, err := s.Queries.GetFeedFollowsByUser(ctx, user.ID)
+	feedFollowsByUser, err := s.Feeds.Following(ctx, user)
 	if err != nil {
 		return err
 	}
 
-	for _, feed_follow := range feedFollowsByUser {
-		fmt.Println(feed_follow.FeedName)
+	return output.Render(os.Stdout, format, feedFollowsByUser, output.View[database.GetFeedFollowsByUserRow]{
+		Columns: []output.Column[database.GetFeedFollowsByUserRow]{
+			{Header: "NAME", Value: func(f database.GetFeedFollowsByUserRow) any { return f.FeedName }},
+			{Header: "URL", Value: func(f database.GetFeedFollowsByUserRow) any { return f.FeedUrl }},
+			{Header: "CATEGORY", Value: func(f database.GetFeedFollowsByUserRow) any { return f.Category.String }},
+			{Header: "FOLLOWED", Value: func(f database.GetFeedFollowsByUserRow) any { return f.CreatedAt }},
+		},
+		Record: func(f database.GetFeedFollowsByUserRow) any { return api.FeedFollowFromDB(f) },
+	})
+}
+
+func Unfollow(ctx context.Context, s *conf.State, c Command, user database.User) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	return s.Feeds.Unfollow(ctx, user, c.Args[1])
+}
+
+func Browse(ctx context.Context, s *conf.State, c Command, user database.User) error {
+	format := output.FromFlags(c.Flags)
+	limit := utils.ParseLimit(c.Args, 2)
+
+	opts := service.BrowseOptions{
+		Feed:     c.String("feed"),
+		Category: c.String("category"),
+		Unread:   c.Bool("unread"),
+		Starred:  c.Bool("starred"),
+		Limit:    limit,
+		Offset:   int32(c.Int("offset")),
+	}
+
+	var err error
+	opts.Since, err = utils.ParseNullDate(c.String("since"))
+	if err != nil {
+		return err
+	}
+
+	opts.Until, err = utils.ParseNullDate(c.String("until"))
+	if err != nil {
+		return err
+	}
+
+	if cursor := c.String("cursor"); cursor != "" {
+		after, err := service.ParseCursor(cursor)
+		if err != nil {
+			return err
+		}
+		opts.Cursor = &after
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	posts, err := s.Posts.Browse(ctx, user, opts)
+	if err != nil {
+		log.Printf("Error while getting posts from db: %v", err)
+		return err
+	}
+
+	if len(posts) == 0 && format.Human() {
+		log.Println("No posts found")
+		return nil
+	}
+
+	err = output.Render(os.Stdout, format, posts, output.View[database.Post]{
+		Columns: []output.Column[database.Post]{
+			{Header: "PUBLISHED", Value: func(p database.Post) any { return p.PublishedAt }},
+			{Header: "TITLE", Value: func(p database.Post) any { return p.Title }},
+			{Header: "URL", Value: func(p database.Post) any { return p.Url }},
+			{Header: "ID", Value: func(p database.Post) any { return p.ID }},
+		},
+		Record: func(p database.Post) any { return api.PostFromDB(p) },
+	})
+	if err != nil {
+		return err
+	}
+
+	// The cursor goes to stderr for the other formats so stdout stays
+	// parseable.
+	if len(posts) == int(limit) {
+		next := fmt.Sprintf("Next page: --cursor %v", service.CursorAfter(posts[len(posts)-1]))
+		if format.Human() {
+			fmt.Println(next)
+		} else {
+			fmt.Fprintln(os.Stderr, next)
+		}
 	}
 
 	return nil
 }
 
-func Unfollow(s *conf.State, c Command, user database.User) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func Publish(ctx context.Context, s *conf.State, c Command, user database.User) error {
+	path := c.Args[1]
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	feed, err := s.Queries.GetFeedByURL(ctx, c.Args[1])
+	feed, err := s.Posts.AggregatedFeed(ctx, user, c.String("category"), int32(c.Int("limit")))
 	if err != nil {
 		return err
 	}
 
-	deleteParams := database.DeleteFeedFollowParams{UserID: user.ID, FeedID: feed.ID}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	err = rss.Write(file, c.String("format"), feed)
+	if err != nil {
+		file.Close()
+		return err
+	}
 
-	err = s.Queries.DeleteFeedFollow(ctx, deleteParams)
+	err = file.Close()
 	if err != nil {
 		return err
 	}
 
+	fmt.Printf("Published %v posts to %v\n", len(feed.Items), path)
+
 	return nil
 }
 
-func Browse(s *conf.State, c Command, user database.User) error {
-	limit := utils.ParseLimit(c.Args, 2)
-
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func FeedToken(ctx context.Context, s *conf.State, c Command, user database.User) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	postsParams := database.GetPostsByUserParams{
-		UserID: user.ID,
-		Limit:  limit,
+	token, err := s.Users.NewFeedToken(ctx, user)
+	if err != nil {
+		return err
 	}
-	posts, err := s.Queries.GetPostsByUser(ctx, postsParams)
+
+	fmt.Printf("Feed token: %v\n", token)
+	fmt.Printf("Subscribe to /users/%v/feed.rss?token=%v on the serve address\n", user.UserName, token)
+
+	return nil
+}
+
+func Search(ctx context.Context, s *conf.State, c Command, user database.User) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	results, err := s.Posts.Search(ctx, user, strings.Join(c.Args[1:], " "), int32(c.Int("limit")))
 	if err != nil {
-		log.Printf("Error while getting posts from db: %v", err)
 		return err
 	}
 
-	if len(posts) == 0 {
-		log.Println("No posts found")
+	if len(results) == 0 {
+		fmt.Println("No posts found")
 		return nil
 	}
 
-	for _, post := range posts {
-		fmt.Println(post)
+	// ts_headline marks the matches with << and >>, render them in bold.
+	highlight := strings.NewReplacer("<<", "\033[1m", ">>", "\033[0m")
+
+	for _, result := range results {
+		fmt.Printf("* %v (%v)\n", result.Title, result.FeedName)
+		fmt.Printf("  %v\n", result.Url)
+		fmt.Printf("  %v\n", highlight.Replace(result.Snippet))
+	}
+
+	return nil
+}
+
+func ReadPost(ctx context.Context, s *conf.State, c Command, user database.User) error {
+	return updatePostState(ctx, c, "Marked as read", func(ctx context.Context, postID uuid.UUID) (database.Post, error) {
+		return s.Posts.MarkRead(ctx, user, postID)
+	})
+}
+
+func UnreadPost(ctx context.Context, s *conf.State, c Command, user database.User) error {
+	return updatePostState(ctx, c, "Marked as unread", func(ctx context.Context, postID uuid.UUID) (database.Post, error) {
+		return s.Posts.MarkUnread(ctx, user, postID)
+	})
+}
+
+func StarPost(ctx context.Context, s *conf.State, c Command, user database.User) error {
+	return updatePostState(ctx, c, "Starred", func(ctx context.Context, postID uuid.UUID) (database.Post, error) {
+		return s.Posts.Star(ctx, user, postID)
+	})
+}
+
+func UnstarPost(ctx context.Context, s *conf.State, c Command, user database.User) error {
+	return updatePostState(ctx, c, "Unstarred", func(ctx context.Context, postID uuid.UUID) (database.Post, error) {
+		return s.Posts.Unstar(ctx, user, postID)
+	})
+}
+
+// Parses the post id argument of the read, unread, star and unstar commands
+// and applies the update to it.
+func updatePostState(ctx context.Context, c Command, done string, update func(context.Context, uuid.UUID) (database.Post, error)) error {
+	postID, err := uuid.Parse(c.Args[1])
+	if err != nil {
+		return fmt.Errorf("invalid post_id %q", c.Args[1])
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	post, err := update(ctx, postID)
+	if err != nil {
+		return err
 	}
 
+	fmt.Printf("%v: %v\n", done, post.Title)
+
 	return nil
 }
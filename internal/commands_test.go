@@ -0,0 +1,1551 @@
+package internal
+
+import (
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Alb3G/gator/internal/backup"
+	conf "github.com/Alb3G/gator/internal/config"
+	"github.com/Alb3G/gator/internal/database"
+	"github.com/Alb3G/gator/internal/database/sqlite"
+	"github.com/Alb3G/gator/internal/fakedb"
+	"github.com/Alb3G/gator/internal/migrate"
+	"github.com/Alb3G/gator/internal/service"
+	"github.com/Alb3G/gator/sql/schema"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	goPostID   = uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	rustPostID = uuid.MustParse("00000000-0000-0000-0000-000000000002")
+)
+
+const (
+	blogURL = "https://blog.example.com/rss"
+	newsURL = "https://news.example.com/atom"
+)
+
+type testEnv struct {
+	state *conf.State
+	db    database.TxQuerier
+	dir   string
+	// Session alice was logged in with.
+	token string
+}
+
+// Every test user has "<name>-password" as password, hashed once with the
+// minimum cost to keep the tests fast.
+var testPasswordHashes = map[string]string{}
+
+func testPasswordHash(t *testing.T, userName string) sql.NullString {
+	t.Helper()
+
+	hash, ok := testPasswordHashes[userName]
+	if !ok {
+		b, err := bcrypt.GenerateFromPassword([]byte(userName+"-password"), bcrypt.MinCost)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hash = string(b)
+		testPasswordHashes[userName] = hash
+	}
+
+	return sql.NullString{String: hash, Valid: true}
+}
+
+func mustUser(t *testing.T, db database.Querier, userName string, password sql.NullString, isAdmin bool) database.User {
+	t.Helper()
+
+	user, err := db.CreateUser(context.Background(), database.CreateUserParams{ID: uuid.New(), UserName: userName, PasswordHash: password, IsAdmin: isAdmin})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+// Makes the password prompts read the given passwords, in order.
+func typePasswords(t *testing.T, passwords ...string) {
+	t.Helper()
+
+	prompt := readPassword
+	t.Cleanup(func() { readPassword = prompt })
+
+	readPassword = func(context.Context, string) (string, error) {
+		if len(passwords) == 0 {
+			return "", io.EOF
+		}
+		password := passwords[0]
+		passwords = passwords[1:]
+		return password, nil
+	}
+}
+
+// The commands run against the in-memory fake and a real SQLite database,
+// which keeps the fake honest.
+var backends = []struct {
+	name string
+	open func(t *testing.T, dir string) database.TxQuerier
+}{
+	{
+		name: "fake",
+		open: func(t *testing.T, dir string) database.TxQuerier {
+			return fakedb.New()
+		},
+	},
+	{
+		name: "sqlite",
+		open: func(t *testing.T, dir string) database.TxQuerier {
+			db, err := sqlite.Open(filepath.Join(dir, "gator.db"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { db.Close() })
+
+			migrator, err := migrate.New(db, schema.SQLiteMigrations(), migrate.SQLite)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = migrator.Up(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			return sqlite.New(db)
+		},
+	},
+}
+
+// Builds a state with two users, alice logged in as the admin, following the
+// blog in the tech category, and bob who added the news feed. The config file
+// is written to a temporary home directory.
+func newTestEnv(t *testing.T, open func(t *testing.T, dir string) database.TxQuerier) *testEnv {
+	t.Helper()
+
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+
+	db := open(t, dir)
+	s := newTestState(t, db)
+	ctx := context.Background()
+
+	alice := mustUser(t, db, "alice", testPasswordHash(t, "alice"), true)
+	bob := mustUser(t, db, "bob", testPasswordHash(t, "bob"), false)
+
+	blog, err := db.CreateFeed(ctx, database.CreateFeedParams{ID: uuid.New(), Name: "Blog", Url: blogURL, UserID: alice.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.Feeds.Follow(ctx, alice, blogURL, "tech")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.Feeds.Add(ctx, bob, "News", newsURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	posts := []database.UpsertPostParams{
+		{ID: goPostID, Title: "Go generics", Url: "https://blog.example.com/go", Description: sql.NullString{String: "Type parameters in Go", Valid: true}, PublishedAt: time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC), FeedID: blog.ID},
+		{ID: rustPostID, Title: "Rust traits", Url: "https://blog.example.com/rust", Description: sql.NullString{String: "Traits in Rust", Valid: true}, PublishedAt: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), FeedID: blog.ID},
+	}
+	for _, post := range posts {
+		_, err = db.UpsertPost(ctx, post)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, token, err := s.Users.Login(ctx, "alice", "alice-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Config.CurrentUserName = "alice"
+	s.Config.SessionToken = token
+
+	return &testEnv{state: s, db: db, dir: dir, token: token}
+}
+
+// Builds a state on db. Its migrator is only asked for the latest schema
+// version, which backups record, so it doesn't need the database.
+func newTestState(t *testing.T, db database.TxQuerier) *conf.State {
+	t.Helper()
+
+	migrations, err := migrate.New(nil, schema.SQLiteMigrations(), migrate.SQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := conf.NewState(&conf.Config{DbUrl: "postgres://test"}, db)
+	s.Migrations = migrations
+
+	return s
+}
+
+// Makes alice follow the news feed, which publishes the Go post of the blog
+// again a day later with the guid "1".
+func shareGoPost(t *testing.T, env *testEnv) {
+	t.Helper()
+
+	ctx := context.Background()
+	alice, _ := env.db.GetUserByName(ctx, "alice")
+	_, err := env.state.Feeds.Follow(ctx, alice, newsURL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	news, _ := env.db.GetFeedByURL(ctx, newsURL)
+	_, err = env.db.UpsertPost(ctx, database.UpsertPostParams{ID: uuid.New(), Title: "Go generics, shared", Url: "https://blog.example.com/go", PublishedAt: time.Date(2024, 5, 3, 0, 0, 0, 0, time.UTC), FeedID: news.ID, Guid: sql.NullString{String: "1", Valid: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Logs the user in with their test password.
+func (env *testEnv) login(t *testing.T, userName string) {
+	t.Helper()
+
+	_, token, err := env.state.Users.Login(context.Background(), userName, userName+"-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.state.Config.CurrentUserName = userName
+	env.state.Config.SessionToken = token
+}
+
+// Makes the confirmation prompts get the given answer.
+func answerConfirm(t *testing.T, answer bool) {
+	t.Helper()
+
+	prompt := confirm
+	t.Cleanup(func() { confirm = prompt })
+
+	confirm = func(string) (bool, error) {
+		return answer, nil
+	}
+}
+
+// Reads the only snapshot written by the operation op, returning its header
+// and the number of rows of every table.
+func readSnapshot(t *testing.T, env *testEnv, op string) (backup.Header, map[string]int) {
+	t.Helper()
+
+	paths, err := filepath.Glob(filepath.Join(env.dir, ".gator", "snapshots", op+"-*.jsonl.gz"))
+	if err != nil || len(paths) != 1 {
+		t.Fatalf("snapshots = %v, %v, want one", paths, err)
+	}
+
+	f, err := os.Open(paths[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec := json.NewDecoder(gz)
+
+	var header backup.Header
+	err = dec.Decode(&header)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows := map[string]int{}
+	for dec.More() {
+		var line struct {
+			Table string `json:"table"`
+		}
+		err = dec.Decode(&line)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows[line.Table]++
+	}
+
+	return header, rows
+}
+
+// Writes the database of env to a backup archive at path and adds carol, so
+// a restore has something to replace.
+func backupAndChange(t *testing.T, env *testEnv, path string) {
+	t.Helper()
+
+	_, err := writeArchive(context.Background(), env.db, path, env.state.Migrations.Latest())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustUser(t, env.db, "carol", testPasswordHash(t, "carol"), false)
+}
+
+// Writes an archive with the given header and lines, to test broken archives.
+func writeTestArchive(t *testing.T, path string, header backup.Header, lines ...string) {
+	t.Helper()
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	err = json.NewEncoder(gz).Encode(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range lines {
+		_, err = io.WriteString(gz, line+"\n")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = gz.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Runs f and returns everything it printed to stdout.
+func captureStdout(t *testing.T, f func() error) (string, error) {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	out := make(chan string)
+	go func() {
+		b, _ := io.ReadAll(r)
+		out <- string(b)
+	}()
+
+	runErr := f()
+	w.Close()
+
+	return <-out, runErr
+}
+
+const subscriptionsOPML = `<?xml version="1.0" encoding="UTF-8"?>
+<opml version="2.0">
+  <head><title>subs</title></head>
+  <body>
+    <outline text="Blog" type="rss" xmlUrl="https://blog.example.com/rss"/>
+    <outline text="Dev">
+      <outline text="Lobsters" type="rss" xmlUrl="https://lobste.rs/rss"/>
+    </outline>
+  </body>
+</opml>`
+
+func TestAggStopsWhenCancelled(t *testing.T) {
+	env := newTestEnv(t, backends[0].open)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	out, err := captureStdout(t, func() error {
+		return NewCommands().Run(ctx, env.state, Command{Name: "agg", Args: []string{"agg", "1h"}})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(out, "Stopped collecting feeds") {
+		t.Errorf("output = %q, want agg to stop", out)
+	}
+}
+
+func TestHiddenCommands(t *testing.T) {
+	cmds := NewCommands()
+
+	for _, name := range cmds.names() {
+		if name == "__complete" {
+			t.Error("__complete is listed by help and the completion scripts")
+		}
+	}
+
+	var b strings.Builder
+	cmds.writeFishCompletion(&b)
+	if strings.Contains(b.String(), "-a __complete") {
+		t.Error("__complete is completed as a command")
+	}
+}
+
+func TestCommands(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		passwords  []string
+		setup      func(t *testing.T, env *testEnv)
+		wantErr    string
+		wantOutput []string
+		check      func(t *testing.T, env *testEnv)
+	}{
+		{
+			name:    "unknown command",
+			args:    []string{"nope"},
+			wantErr: `unknown command "nope", run ` + "`gator help`",
+		},
+		{
+			name:       "help",
+			args:       []string{"help"},
+			wantOutput: []string{"Usage: gator <command> [flags] [args]", "  addfeed      Add a feed and follow it\n", "  unstar       Remove the star of a post\n"},
+		},
+		{
+			name: "help command",
+			args: []string{"help", "browse"},
+			wantOutput: []string{
+				"Usage: gator browse [flags] [limit]\n\nShow the latest posts of the feeds you follow\n",
+				"Arguments:\n  limit  number of posts, 1 to 100 (default 2)\n",
+				"  --offset int         number of posts to skip\n",
+				"  --unread             only show posts not marked as read\n",
+				"  --format format      output format: table, json, jsonl, csv or template (default table)\n",
+			},
+		},
+		{
+			name:    "help unknown command",
+			args:    []string{"help", "nope"},
+			wantErr: `unknown command "nope"`,
+		},
+		{
+			name:       "help flag",
+			args:       []string{"search", "--help"},
+			wantOutput: []string{"Usage: gator search [flags] <query>...", "  --limit int  maximum number of results (default 10)"},
+		},
+		{
+			name:    "unknown flag",
+			args:    []string{"browse", "--newest"},
+			wantErr: "flag provided but not defined: -newest\nUsage: gator browse [flags] [limit]\nRun `gator help browse` for details",
+		},
+		{
+			name:       "completion",
+			args:       []string{"completion", "bash"},
+			wantOutput: []string{"_gator() {", "compgen -W \"addfeed agg backup browse", "complete -o default -F _gator gator\n"},
+		},
+		{
+			name:       "completion zsh",
+			args:       []string{"completion", "zsh"},
+			wantOutput: []string{"#compdef gator\n", "'migrate:Apply, roll back or list the database migrations'", "compdef _gator gator"},
+		},
+		{
+			name:       "completion fish",
+			args:       []string{"completion", "fish"},
+			wantOutput: []string{"complete -c gator -n '__fish_seen_subcommand_from browse' -l unread -d 'only show posts not marked as read'\n"},
+		},
+		{
+			name:    "completion unknown shell",
+			args:    []string{"completion", "powershell"},
+			wantErr: `unknown shell "powershell"`,
+		},
+		{
+			name:       "complete usernames",
+			args:       []string{"__complete", "login", ""},
+			wantOutput: []string{"alice\nbob\n"},
+		},
+		{
+			name:       "complete feeds to follow",
+			args:       []string{"__complete", "follow", "https://"},
+			wantOutput: []string{newsURL + "\n"},
+		},
+		{
+			name:       "complete feeds to unfollow",
+			args:       []string{"__complete", "unfollow", "https://"},
+			wantOutput: []string{blogURL + "\n"},
+		},
+		{
+			name:       "complete fixed values",
+			args:       []string{"__complete", "migrate", "s"},
+			wantOutput: []string{"status\n"},
+		},
+		{
+			name:    "too many args",
+			args:    []string{"follow", blogURL, newsURL},
+			wantErr: `unexpected arg "` + newsURL + `"`,
+		},
+		{
+			name:       "login",
+			args:       []string{"login", "bob"},
+			passwords:  []string{"bob-password"},
+			wantOutput: []string{"New User has been set."},
+			check: func(t *testing.T, env *testEnv) {
+				if env.state.Config.CurrentUserName != "bob" {
+					t.Errorf("current user = %q, want bob", env.state.Config.CurrentUserName)
+				}
+				user, err := env.state.Users.GetBySession(context.Background(), env.state.Config.SessionToken)
+				if err != nil || user.UserName != "bob" {
+					t.Errorf("user of the session = %q, %v, want bob", user.UserName, err)
+				}
+				path := filepath.Join(env.dir, conf.CONFIG_FILE)
+				b, err := os.ReadFile(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !strings.Contains(string(b), `"current_user_name":"bob"`) || !strings.Contains(string(b), `"session_token":"`+env.state.Config.SessionToken+`"`) {
+					t.Errorf("config file = %s, want the session of bob", b)
+				}
+				info, err := os.Stat(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if info.Mode().Perm() != 0600 {
+					t.Errorf("config file mode = %v, want 0600", info.Mode().Perm())
+				}
+			},
+		},
+		{
+			name:      "login wrong password",
+			args:      []string{"login", "bob"},
+			passwords: []string{"alice-password"},
+			wantErr:   "wrong password for user bob",
+			check: func(t *testing.T, env *testEnv) {
+				if env.state.Config.CurrentUserName != "alice" {
+					t.Errorf("current user = %q, want alice", env.state.Config.CurrentUserName)
+				}
+			},
+		},
+		{
+			name:    "login without password",
+			args:    []string{"login", "bob"},
+			wantErr: "EOF",
+		},
+		{
+			name: "login user without a password",
+			args: []string{"login", "dave"},
+			setup: func(t *testing.T, env *testEnv) {
+				mustUser(t, env.db, "dave", sql.NullString{}, false)
+			},
+			passwords: []string{"dave-password"},
+			wantErr:   "user dave has no password yet, an admin can set it with `gator setpassword dave`",
+			check: func(t *testing.T, env *testEnv) {
+				dave, _ := env.db.GetUserByName(context.Background(), "dave")
+				if dave.PasswordHash.Valid {
+					t.Error("the refused login set the password of dave")
+				}
+			},
+		},
+		{
+			name: "setpassword",
+			args: []string{"setpassword", "dave"},
+			setup: func(t *testing.T, env *testEnv) {
+				mustUser(t, env.db, "dave", sql.NullString{}, false)
+			},
+			passwords:  []string{"dave-password", "dave-password"},
+			wantOutput: []string{"Password of dave set"},
+			check: func(t *testing.T, env *testEnv) {
+				env.login(t, "dave")
+			},
+		},
+		{
+			name:      "setpassword replaces a password",
+			args:      []string{"setpassword", "bob"},
+			passwords: []string{"new-bob-password", "new-bob-password"},
+			check: func(t *testing.T, env *testEnv) {
+				_, _, err := env.state.Users.Login(context.Background(), "bob", "bob-password")
+				if err == nil {
+					t.Error("bob logged in with the previous password")
+				}
+				_, _, err = env.state.Users.Login(context.Background(), "bob", "new-bob-password")
+				if err != nil {
+					t.Errorf("login with the new password of bob: %v", err)
+				}
+			},
+		},
+		{
+			name: "setpassword not admin",
+			args: []string{"setpassword", "alice"},
+			setup: func(t *testing.T, env *testEnv) {
+				env.login(t, "bob")
+			},
+			passwords: []string{"new-alice-password", "new-alice-password"},
+			wantErr:   "user bob isn't an admin",
+		},
+		{
+			name:      "setpassword wrong setup token",
+			args:      []string{"setpassword", "--token", "nope", "bob"},
+			passwords: []string{"new-bob-password", "new-bob-password"},
+			wantErr:   "wrong setup token for user bob",
+		},
+		{
+			name:    "login without username",
+			args:    []string{"login"},
+			wantErr: "missing username arg\nUsage: gator login <username>",
+		},
+		{
+			name:      "login unknown user",
+			args:      []string{"login", "carol"},
+			passwords: []string{"carol-password"},
+			wantErr:   "user carol not found",
+		},
+		{
+			name:       "register",
+			args:       []string{"register", "carol"},
+			passwords:  []string{"carol-password", "carol-password"},
+			wantOutput: []string{"User created successfully", "New User has been set."},
+			check: func(t *testing.T, env *testEnv) {
+				user, err := env.db.GetUserByName(context.Background(), "carol")
+				if err != nil {
+					t.Errorf("carol was not created: %v", err)
+				}
+				if user.PasswordHash.String == "" || strings.Contains(user.PasswordHash.String, "carol-password") {
+					t.Errorf("password hash of carol = %q", user.PasswordHash.String)
+				}
+				if env.state.Config.CurrentUserName != "carol" {
+					t.Errorf("current user = %q, want carol", env.state.Config.CurrentUserName)
+				}
+				_, err = env.state.Users.GetBySession(context.Background(), env.state.Config.SessionToken)
+				if err != nil {
+					t.Errorf("session of carol: %v", err)
+				}
+			},
+		},
+		{
+			name:      "register existing user",
+			args:      []string{"register", "bob"},
+			passwords: []string{"bob-password", "bob-password"},
+			wantErr:   "user bob already exists",
+		},
+		{
+			name:      "register mistyped password",
+			args:      []string{"register", "carol"},
+			passwords: []string{"carol-password", "carol-pasword"},
+			wantErr:   "the passwords don't match",
+		},
+		{
+			name:      "register short password",
+			args:      []string{"register", "carol"},
+			passwords: []string{"carol", "carol"},
+			wantErr:   "the password must have at least 8 characters",
+		},
+		{
+			name:       "passwd",
+			args:       []string{"passwd"},
+			passwords:  []string{"alice-password", "new-password", "new-password"},
+			wantOutput: []string{"Password changed"},
+			check: func(t *testing.T, env *testEnv) {
+				ctx := context.Background()
+				_, err := env.state.Users.GetBySession(ctx, env.token)
+				if err == nil {
+					t.Error("the previous session of alice is still open")
+				}
+				_, err = env.state.Users.GetBySession(ctx, env.state.Config.SessionToken)
+				if err != nil {
+					t.Errorf("new session of alice: %v", err)
+				}
+				_, _, err = env.state.Users.Login(ctx, "alice", "new-password")
+				if err != nil {
+					t.Errorf("login with the new password: %v", err)
+				}
+			},
+		},
+		{
+			name:      "passwd wrong current password",
+			args:      []string{"passwd"},
+			passwords: []string{"bob-password", "new-password", "new-password"},
+			wantErr:   "wrong current password",
+		},
+		{
+			name:    "register without username",
+			args:    []string{"register"},
+			wantErr: "missing username arg",
+		},
+		{
+			name:       "reset",
+			args:       []string{"reset", "--yes"},
+			wantOutput: []string{"Snapshot of 2 users, 2 feeds, 2 follows, 2 posts, 0 post states written to ", "Reset done."},
+			check: func(t *testing.T, env *testEnv) {
+				users, _ := env.db.GetUsers(context.Background())
+				feeds, _ := env.db.GetFeeds(context.Background())
+				if len(users) != 0 || len(feeds) != 0 {
+					t.Errorf("reset left %v users and %v feeds", len(users), len(feeds))
+				}
+				header, rows := readSnapshot(t, env, "reset")
+				if header.Format != backup.Format || header.Version != backup.Version {
+					t.Errorf("snapshot header = %+v", header)
+				}
+				if rows["users"] != 2 || rows["feeds"] != 2 || rows["feed_follows"] != 2 || rows["posts"] != 2 {
+					t.Errorf("snapshot rows = %v", rows)
+				}
+			},
+		},
+		{
+			name:  "reset posts confirmed",
+			args:  []string{"reset", "--posts"},
+			setup: func(t *testing.T, env *testEnv) { answerConfirm(t, true) },
+			check: func(t *testing.T, env *testEnv) {
+				posts, _ := env.db.GetPosts(context.Background())
+				feeds, _ := env.db.GetFeeds(context.Background())
+				if len(posts) != 0 || len(feeds) != 2 {
+					t.Errorf("reset of the posts left %v posts and %v feeds", len(posts), len(feeds))
+				}
+				_, rows := readSnapshot(t, env, "reset")
+				if rows["posts"] != 2 {
+					t.Errorf("snapshot rows = %v, want the posts", rows)
+				}
+			},
+		},
+		{
+			name:       "reset declined",
+			args:       []string{"reset"},
+			setup:      func(t *testing.T, env *testEnv) { answerConfirm(t, false) },
+			wantOutput: []string{"Reset cancelled."},
+			check: func(t *testing.T, env *testEnv) {
+				users, _ := env.db.GetUsers(context.Background())
+				if len(users) != 2 {
+					t.Errorf("declined reset left %v users", len(users))
+				}
+				_, err := os.Stat(filepath.Join(env.dir, ".gator"))
+				if !os.IsNotExist(err) {
+					t.Errorf("declined reset wrote a snapshot: %v", err)
+				}
+			},
+		},
+		{
+			name:    "reset without a terminal to confirm",
+			args:    []string{"reset"},
+			wantErr: "pass --yes to skip the confirmation",
+		},
+		{
+			name: "reset feeds",
+			args: []string{"reset", "--yes", "--feeds"},
+			check: func(t *testing.T, env *testEnv) {
+				ctx := context.Background()
+				users, _ := env.db.GetUsers(ctx)
+				feeds, _ := env.db.GetFeeds(ctx)
+				follows, _ := env.db.GetFeedFollows(ctx)
+				posts, _ := env.db.GetPosts(ctx)
+				if len(users) != 2 || len(feeds) != 0 || len(follows) != 0 || len(posts) != 0 {
+					t.Errorf("reset of the feeds left %v users, %v feeds, %v follows and %v posts", len(users), len(feeds), len(follows), len(posts))
+				}
+			},
+		},
+		{
+			name: "reset user",
+			args: []string{"reset", "--yes", "--user", "bob"},
+			check: func(t *testing.T, env *testEnv) {
+				ctx := context.Background()
+				_, err := env.db.GetUserByName(ctx, "bob")
+				if err == nil {
+					t.Error("bob was not deleted")
+				}
+				_, err = env.db.GetFeedByURL(ctx, newsURL)
+				if err == nil {
+					t.Error("the feed added by bob was not deleted")
+				}
+				posts, _ := env.db.GetPosts(ctx)
+				if len(posts) != 2 {
+					t.Errorf("reset of bob left %v posts of the blog, want 2", len(posts))
+				}
+			},
+		},
+		{
+			name:    "reset logged in user",
+			args:    []string{"reset", "--yes", "--user", "alice"},
+			wantErr: "can't delete the logged in user alice",
+		},
+		{
+			name:    "reset unknown user",
+			args:    []string{"reset", "--yes", "--user", "carol"},
+			wantErr: "user carol not found",
+		},
+		{
+			name:    "reset several scopes",
+			args:    []string{"reset", "--yes", "--posts", "--feeds"},
+			wantErr: "only one of --posts, --feeds and --user can be given",
+		},
+		{
+			name:    "reset without admin rights",
+			args:    []string{"reset", "--yes"},
+			setup:   func(t *testing.T, env *testEnv) { env.login(t, "bob") },
+			wantErr: "user bob isn't an admin",
+			check: func(t *testing.T, env *testEnv) {
+				users, _ := env.db.GetUsers(context.Background())
+				if len(users) != 2 {
+					t.Errorf("refused reset left %v users", len(users))
+				}
+			},
+		},
+		{
+			name:       "backup",
+			args:       []string{"backup", "gator.jsonl.gz"},
+			setup:      func(t *testing.T, env *testEnv) { os.WriteFile("gator.jsonl.gz", []byte("last night"), 0644) },
+			wantOutput: []string{"Backup of 2 users, 2 feeds, 2 follows, 2 posts, 0 post states written to gator.jsonl.gz"},
+			check: func(t *testing.T, env *testEnv) {
+				info, err := os.Stat("gator.jsonl.gz")
+				if err != nil || info.Mode().Perm() != 0600 {
+					t.Fatalf("backup file = %v, %v, want it only readable by the user", info, err)
+				}
+				f, _ := os.Open("gator.jsonl.gz")
+				defer f.Close()
+				_, err = backup.Read(context.Background(), fakedb.New(), f, env.state.Migrations.Latest())
+				if err != nil {
+					t.Errorf("backup replacing an old file isn't readable: %v", err)
+				}
+			},
+		},
+		{
+			name:    "backup without admin rights",
+			args:    []string{"backup", "gator.jsonl.gz"},
+			setup:   func(t *testing.T, env *testEnv) { env.login(t, "bob") },
+			wantErr: "user bob isn't an admin",
+		},
+		{
+			name:    "backup without file",
+			args:    []string{"backup"},
+			wantErr: "missing file arg",
+		},
+		{
+			name:       "restore",
+			args:       []string{"restore", "--yes", "gator.jsonl.gz"},
+			setup:      func(t *testing.T, env *testEnv) { backupAndChange(t, env, "gator.jsonl.gz") },
+			wantOutput: []string{"Snapshot of 3 users, 2 feeds, 2 follows, 2 posts, 0 post states written to", "Restored 2 users, 2 feeds, 2 follows, 2 posts, 0 post states from gator.jsonl.gz", "gator login <username>"},
+			check: func(t *testing.T, env *testEnv) {
+				ctx := context.Background()
+				alice, err := env.db.GetUserByName(ctx, "alice")
+				if err != nil || !alice.IsAdmin {
+					t.Fatalf("restored alice = %+v, %v, want the admin", alice, err)
+				}
+				follows, _ := env.db.GetFeedFollowsByUser(ctx, alice.ID)
+				if len(follows) != 1 || follows[0].Category.String != "tech" {
+					t.Errorf("restored follows of alice = %+v", follows)
+				}
+				_, err = env.db.GetUserByName(ctx, "carol")
+				if !errors.Is(err, sql.ErrNoRows) {
+					t.Errorf("carol after restore: %v, want her replaced", err)
+				}
+				_, _, err = env.state.Users.Login(ctx, "alice", "alice-password")
+				if err != nil {
+					t.Errorf("login after restore: %v", err)
+				}
+				header, rows := readSnapshot(t, env, "restore")
+				if header.SchemaVersion != env.state.Migrations.Latest() || rows["users"] != 3 {
+					t.Errorf("snapshot = %+v, %v, want the 3 users before the restore", header, rows)
+				}
+			},
+		},
+		{
+			name: "restore confirmed",
+			args: []string{"restore", "gator.jsonl.gz"},
+			setup: func(t *testing.T, env *testEnv) {
+				backupAndChange(t, env, "gator.jsonl.gz")
+				answerConfirm(t, true)
+			},
+			wantOutput: []string{"Restored 2 users"},
+		},
+		{
+			name: "restore cancelled",
+			args: []string{"restore", "gator.jsonl.gz"},
+			setup: func(t *testing.T, env *testEnv) {
+				backupAndChange(t, env, "gator.jsonl.gz")
+				answerConfirm(t, false)
+			},
+			wantOutput: []string{"Restore cancelled."},
+			check: func(t *testing.T, env *testEnv) {
+				_, err := env.db.GetUserByName(context.Background(), "carol")
+				if err != nil {
+					t.Errorf("carol after a cancelled restore: %v", err)
+				}
+			},
+		},
+		{
+			name: "restore without admin rights",
+			args: []string{"restore", "--yes", "gator.jsonl.gz"},
+			setup: func(t *testing.T, env *testEnv) {
+				backupAndChange(t, env, "gator.jsonl.gz")
+				env.login(t, "bob")
+			},
+			wantErr: "user bob isn't an admin",
+		},
+		{
+			name:    "restore missing file",
+			args:    []string{"restore", "--yes", "missing.jsonl.gz"},
+			wantErr: "no such file",
+		},
+		{
+			name:    "restore not a backup",
+			args:    []string{"restore", "--yes", "notes.txt"},
+			setup:   func(t *testing.T, env *testEnv) { os.WriteFile("notes.txt", []byte("not gzip"), 0644) },
+			wantErr: "not a gator backup",
+		},
+		{
+			name: "restore newer version",
+			args: []string{"restore", "--yes", "gator.jsonl.gz"},
+			setup: func(t *testing.T, env *testEnv) {
+				writeTestArchive(t, "gator.jsonl.gz", backup.Header{Format: backup.Format, Version: backup.Version + 1, SchemaVersion: env.state.Migrations.Latest()})
+			},
+			wantErr: fmt.Sprintf("unsupported backup version %v", backup.Version+1),
+		},
+		{
+			name: "restore other schema version",
+			args: []string{"restore", "--yes", "gator.jsonl.gz"},
+			setup: func(t *testing.T, env *testEnv) {
+				writeTestArchive(t, "gator.jsonl.gz", backup.Header{Format: backup.Format, Version: backup.Version, SchemaVersion: env.state.Migrations.Latest() - 1})
+			},
+			wantErr: "the backup was written at schema version 17 but the database is at version 18",
+			check: func(t *testing.T, env *testEnv) {
+				users, _ := env.db.GetUsers(context.Background())
+				if len(users) != 2 {
+					t.Errorf("failed restore left %v users, want the 2 it started with", len(users))
+				}
+			},
+		},
+		{
+			name: "restore tables out of order",
+			args: []string{"restore", "--yes", "gator.jsonl.gz"},
+			setup: func(t *testing.T, env *testEnv) {
+				writeTestArchive(t, "gator.jsonl.gz", backup.Header{Format: backup.Format, Version: backup.Version, SchemaVersion: env.state.Migrations.Latest()},
+					`{"table": "users", "row": {"id": "5e2c6a4e-0d4b-4a47-9b5e-3f1b6f0e8c01", "user_name": "carol"}}`,
+					`{"table": "feeds", "row": {"id": "5e2c6a4e-0d4b-4a47-9b5e-3f1b6f0e8c02", "url": "https://carol.example.com", "user_id": "5e2c6a4e-0d4b-4a47-9b5e-3f1b6f0e8c01"}}`,
+					`{"table": "users", "row": {"id": "5e2c6a4e-0d4b-4a47-9b5e-3f1b6f0e8c03", "user_name": "dave"}}`,
+				)
+			},
+			wantErr: `line 4: unknown or out of order table "users"`,
+			check: func(t *testing.T, env *testEnv) {
+				users, _ := env.db.GetUsers(context.Background())
+				if len(users) != 2 {
+					t.Errorf("failed restore left %v users, want the 2 it started with", len(users))
+				}
+			},
+		},
+		{
+			name: "restore truncated",
+			args: []string{"restore", "--yes", "gator.jsonl.gz"},
+			setup: func(t *testing.T, env *testEnv) {
+				backupAndChange(t, env, "gator.jsonl.gz")
+				info, _ := os.Stat("gator.jsonl.gz")
+				os.Truncate("gator.jsonl.gz", info.Size()-10)
+			},
+			wantErr: "unexpected EOF",
+			check: func(t *testing.T, env *testEnv) {
+				users, _ := env.db.GetUsers(context.Background())
+				if len(users) != 3 {
+					t.Errorf("failed restore left %v users, want the 3 it started with", len(users))
+				}
+			},
+		},
+		{
+			name:       "users",
+			args:       []string{"users"},
+			wantOutput: []string{"NAME   CURRENT  ADMIN  CREATED", "alice  *        *", "bob    -        -"},
+		},
+		{
+			name:       "users json",
+			args:       []string{"users", "--json"},
+			wantOutput: []string{`"user_name": "alice",`, `"is_admin": true`, `"current": true`, `"user_name": "bob",`, `"is_admin": false`, `"current": false`},
+		},
+		{
+			name:    "users unknown format",
+			args:    []string{"users", "--format", "yaml"},
+			wantErr: `unknown format "yaml"`,
+		},
+		{
+			name:    "agg without interval",
+			args:    []string{"agg"},
+			wantErr: "missing interval arg",
+		},
+		{
+			name:    "agg invalid interval",
+			args:    []string{"agg", "soon"},
+			wantErr: "invalid duration",
+		},
+		{
+			name:       "feedhealth all healthy",
+			args:       []string{"feedhealth"},
+			wantOutput: []string{"All feeds are healthy"},
+		},
+		{
+			name: "feedhealth failing feed",
+			args: []string{"feedhealth"},
+			setup: func(t *testing.T, env *testEnv) {
+				feed, _ := env.db.GetFeedByURL(context.Background(), newsURL)
+				env.db.MarkFeedFailed(context.Background(), database.MarkFeedFailedParams{
+					ID:        feed.ID,
+					LastError: sql.NullString{String: "status 500", Valid: true},
+				})
+			},
+			wantOutput: []string{"* News (" + newsURL + ")", "consecutive failures: 1", "last success:         never", "last error:           status 500"},
+		},
+		{
+			name:    "serve invalid address",
+			args:    []string{"serve", "localhost:-1"},
+			wantErr: "invalid port",
+		},
+		{
+			name:    "migrate without subcommand",
+			args:    []string{"migrate"},
+			wantErr: "missing subcommand arg",
+		},
+		{
+			name:    "migrate unknown subcommand",
+			args:    []string{"migrate", "sideways"},
+			wantErr: `unknown migrate subcommand "sideways"`,
+		},
+		{
+			name:       "addfeed",
+			args:       []string{"addfeed", "Lobsters", "https://lobste.rs/rss"},
+			wantOutput: []string{"Lobsters"},
+			check: func(t *testing.T, env *testEnv) {
+				alice, _ := env.db.GetUserByName(context.Background(), "alice")
+				follows, _ := env.db.GetFeedFollowsByUser(context.Background(), alice.ID)
+				if len(follows) != 2 {
+					t.Errorf("alice follows %v feeds, want 2", len(follows))
+				}
+			},
+		},
+		{
+			name:    "addfeed existing url",
+			args:    []string{"addfeed", "Blog", blogURL},
+			wantErr: "feed " + blogURL + " already exists",
+		},
+		{
+			name:    "addfeed missing args",
+			args:    []string{"addfeed", "Lobsters"},
+			wantErr: "missing url arg",
+		},
+		{
+			name: "addfeed logged out",
+			args: []string{"addfeed", "Lobsters", "https://lobste.rs/rss"},
+			setup: func(t *testing.T, env *testEnv) {
+				env.state.Config.SessionToken = ""
+			},
+			wantErr: "not logged in, run `gator login <username>`",
+		},
+		{
+			name: "addfeed with a closed session",
+			args: []string{"addfeed", "Lobsters", "https://lobste.rs/rss"},
+			setup: func(t *testing.T, env *testEnv) {
+				env.state.Config.SessionToken = "forged"
+			},
+			wantErr: "the session is closed",
+		},
+		{
+			name:       "feeds",
+			args:       []string{"feeds"},
+			wantOutput: []string{"NAME  URL                            OWNER", "Blog  " + blogURL + "   alice", "News  " + newsURL + "  bob"},
+		},
+		{
+			name:       "feeds csv",
+			args:       []string{"feeds", "--format", "csv"},
+			wantOutput: []string{"name,url,owner,last_fetched,failures\n", "Blog," + blogURL + ",alice,,0\n"},
+		},
+		{
+			name:       "follow",
+			args:       []string{"follow", newsURL},
+			wantOutput: []string{"alice followed: News"},
+		},
+		{
+			name:    "follow already followed",
+			args:    []string{"follow", blogURL},
+			wantErr: "follow of " + blogURL + " already exists",
+		},
+		{
+			name:    "follow unknown feed",
+			args:    []string{"follow", "https://nowhere.example.com"},
+			wantErr: "feed https://nowhere.example.com not found",
+		},
+		{
+			name:    "follow without url",
+			args:    []string{"follow"},
+			wantErr: "missing url arg",
+		},
+		{
+			name:       "following",
+			args:       []string{"following"},
+			wantOutput: []string{"NAME  URL", "Blog  " + blogURL + "  tech"},
+		},
+		{
+			name:       "following template",
+			args:       []string{"following", "--template", "{{.FeedName}} <{{.FeedUrl}}>"},
+			wantOutput: []string{"Blog <" + blogURL + ">\n"},
+		},
+		{
+			name:    "following template format without template",
+			args:    []string{"following", "--format", "template"},
+			wantErr: "the template format needs a --template",
+		},
+		{
+			name: "unfollow",
+			args: []string{"unfollow", blogURL},
+			check: func(t *testing.T, env *testEnv) {
+				alice, _ := env.db.GetUserByName(context.Background(), "alice")
+				follows, _ := env.db.GetFeedFollowsByUser(context.Background(), alice.ID)
+				if len(follows) != 0 {
+					t.Errorf("alice still follows %v feeds", len(follows))
+				}
+			},
+		},
+		{
+			name:    "unfollow without url",
+			args:    []string{"unfollow"},
+			wantErr: "missing url arg",
+		},
+		{
+			name: "import",
+			args: []string{"import", "subs.opml"},
+			setup: func(t *testing.T, env *testEnv) {
+				err := os.WriteFile(filepath.Join(env.dir, "subs.opml"), []byte(subscriptionsOPML), 0644)
+				if err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantOutput: []string{"Imported 2 feeds: 1 created, 1 followed, 1 already followed"},
+		},
+		{
+			name:    "import missing file",
+			args:    []string{"import", "missing.opml"},
+			wantErr: "no such file",
+		},
+		{
+			name:    "import without file",
+			args:    []string{"import"},
+			wantErr: "missing file arg",
+		},
+		{
+			name:       "export to stdout",
+			args:       []string{"export"},
+			wantOutput: []string{"alice subscriptions in gator", `text="tech"`, `xmlUrl="` + blogURL + `"`},
+		},
+		{
+			name:       "export to file",
+			args:       []string{"export", "subs.opml"},
+			wantOutput: []string{"Exported 1 feeds to subs.opml"},
+		},
+		{
+			name:       "browse",
+			args:       []string{"browse"},
+			wantOutput: []string{"Go generics  https://blog.example.com/go", "Rust traits  https://blog.example.com/rust", "Next page: --cursor"},
+		},
+		{
+			name:       "browse with limit",
+			args:       []string{"browse", "1"},
+			wantOutput: []string{"Go generics", "Next page: --cursor"},
+		},
+		{
+			name:       "browse since",
+			args:       []string{"browse", "--since", "2024-05-02"},
+			wantOutput: []string{"Go generics"},
+		},
+		{
+			name:       "browse jsonl",
+			args:       []string{"browse", "--format", "jsonl", "--since", "2024-05-02"},
+			wantOutput: []string{`{"id":"` + goPostID.String() + `",`, `"title":"Go generics",`, `"published_at":"2024-05-02T00:00:00Z",`},
+		},
+		{
+			name:    "browse invalid date",
+			args:    []string{"browse", "--since", "yesterday"},
+			wantErr: "yesterday",
+		},
+		{
+			name:    "browse unknown feed",
+			args:    []string{"browse", "--feed", "News"},
+			wantErr: `you don't follow any feed with url or name "News"`,
+		},
+		{
+			name:    "browse negative offset",
+			args:    []string{"browse", "--offset", "-2"},
+			wantErr: "invalid value -2 for flag --offset",
+		},
+		{
+			name:    "browse invalid cursor",
+			args:    []string{"browse", "--cursor", "???"},
+			wantErr: "cursor",
+		},
+		{
+			name: "browse unread",
+			args: []string{"browse", "--unread"},
+			setup: func(t *testing.T, env *testEnv) {
+				alice, _ := env.db.GetUserByName(context.Background(), "alice")
+				env.db.MarkPostRead(context.Background(), database.MarkPostReadParams{UserID: alice.ID, PostID: goPostID, ReadAt: sql.NullTime{Time: time.Now(), Valid: true}})
+			},
+			wantOutput: []string{"Rust traits"},
+		},
+		{
+			name:       "browse link shared by two feeds",
+			args:       []string{"browse"},
+			setup:      func(t *testing.T, env *testEnv) { shareGoPost(t, env) },
+			wantOutput: []string{"Go generics, shared", "Rust traits"},
+			check: func(t *testing.T, env *testEnv) {
+				alice, _ := env.db.GetUserByName(context.Background(), "alice")
+				posts, err := env.state.Posts.Browse(context.Background(), alice, service.BrowseOptions{Limit: 10})
+				if err != nil || len(posts) != 2 {
+					t.Errorf("browse = %+v, %v, want the shared link once", posts, err)
+				}
+			},
+		},
+		{
+			name:       "publish",
+			args:       []string{"publish", "--format", "atom", "feed.xml"},
+			wantOutput: []string{"Published 2 posts to feed.xml"},
+			check: func(t *testing.T, env *testEnv) {
+				b, err := os.ReadFile("feed.xml")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !strings.Contains(string(b), "<feed") || !strings.Contains(string(b), "Go generics") {
+					t.Errorf("published feed = %s", b)
+				}
+			},
+		},
+		{
+			name:       "publish link shared by two feeds",
+			args:       []string{"publish", "feed.xml"},
+			setup:      func(t *testing.T, env *testEnv) { shareGoPost(t, env) },
+			wantOutput: []string{"Published 2 posts to feed.xml"},
+			check: func(t *testing.T, env *testEnv) {
+				b, err := os.ReadFile("feed.xml")
+				if err != nil {
+					t.Fatal(err)
+				}
+				news, _ := env.db.GetFeedByURL(context.Background(), newsURL)
+				guid := fmt.Sprintf(`<guid isPermaLink="false">urn:gator:%v:1</guid>`, news.ID)
+				if strings.Count(string(b), "https://blog.example.com/go</link>") != 1 || !strings.Contains(string(b), guid) {
+					t.Errorf("published feed = %s, want the shared link once with the guid %v", b, guid)
+				}
+				if strings.Contains(string(b), "<link></link>") {
+					t.Errorf("published feed = %s, want no empty link", b)
+				}
+			},
+		},
+		{
+			name:    "publish limit too large",
+			args:    []string{"publish", "--limit", "4294967296", "feed.xml"},
+			wantErr: "invalid value 4294967296 for flag --limit",
+		},
+		{
+			name:    "publish without file",
+			args:    []string{"publish"},
+			wantErr: "missing file arg",
+		},
+		{
+			name:       "search",
+			args:       []string{"search", "traits"},
+			wantOutput: []string{"* Rust traits (Blog)", "\033[1mTraits\033[0m in Rust"},
+		},
+		{
+			name:       "search no results",
+			args:       []string{"search", "python"},
+			wantOutput: []string{"No posts found"},
+		},
+		{
+			name:    "search without query",
+			args:    []string{"search"},
+			wantErr: "missing query arg",
+		},
+		{
+			name:    "search with a flag after the query",
+			args:    []string{"search", "go", "--limit", "1"},
+			wantErr: "flag --limit given after the arguments, flags go first",
+		},
+		{
+			// Only runs, the fake database doesn't exclude words.
+			name: "search excluding a word spelled like a flag",
+			args: []string{"search", "--", "traits", "-limit"},
+		},
+		{
+			name:    "search negative limit",
+			args:    []string{"search", "--limit", "-1", "go"},
+			wantErr: "invalid value -1 for flag --limit: must be between 0 and 2147483647",
+		},
+		{
+			name:       "read",
+			args:       []string{"read", goPostID.String()},
+			wantOutput: []string{"Marked as read: Go generics"},
+		},
+		{
+			name:       "unread",
+			args:       []string{"unread", goPostID.String()},
+			wantOutput: []string{"Marked as unread: Go generics"},
+		},
+		{
+			name:       "star",
+			args:       []string{"star", rustPostID.String()},
+			wantOutput: []string{"Starred: Rust traits"},
+			check: func(t *testing.T, env *testEnv) {
+				alice, _ := env.db.GetUserByName(context.Background(), "alice")
+				posts, _ := env.db.GetPostsByUser(context.Background(), database.GetPostsByUserParams{UserID: alice.ID, StarredOnly: true, Limit: 10})
+				if len(posts) != 1 || posts[0].ID != rustPostID {
+					t.Errorf("starred posts = %v, want the rust post", posts)
+				}
+			},
+		},
+		{
+			name:       "unstar",
+			args:       []string{"unstar", rustPostID.String()},
+			wantOutput: []string{"Unstarred: Rust traits"},
+		},
+		{
+			name:    "star unknown post",
+			args:    []string{"star", uuid.Nil.String()},
+			wantErr: "post " + uuid.Nil.String() + " not found",
+		},
+		{
+			name:    "star post of a feed not followed",
+			args:    []string{"star", goPostID.String()},
+			setup:   func(t *testing.T, env *testEnv) { env.login(t, "bob") },
+			wantErr: "post " + goPostID.String() + " not found",
+			check: func(t *testing.T, env *testEnv) {
+				states, _ := env.db.GetPostStates(context.Background())
+				if len(states) != 0 {
+					t.Errorf("post states = %+v, want none for a post bob can't browse", states)
+				}
+			},
+		},
+		{
+			name:    "read invalid post id",
+			args:    []string{"read", "42"},
+			wantErr: `invalid post_id "42"`,
+		},
+		{
+			name:    "read without post id",
+			args:    []string{"read"},
+			wantErr: "missing post_id arg",
+		},
+	}
+
+	cmds := NewCommands()
+
+	for _, backend := range backends {
+		for _, tt := range tests {
+			t.Run(backend.name+"/"+tt.name, func(t *testing.T) {
+				env := newTestEnv(t, backend.open)
+				t.Chdir(env.dir)
+
+				typePasswords(t, tt.passwords...)
+				if tt.setup != nil {
+					tt.setup(t, env)
+				}
+
+				out, err := captureStdout(t, func() error {
+					return cmds.Run(t.Context(), env.state, Command{Name: tt.args[0], Args: tt.args})
+				})
+
+				if tt.wantErr != "" {
+					if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+						t.Fatalf("error = %v, want %q", err, tt.wantErr)
+					}
+				} else if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				for _, want := range tt.wantOutput {
+					if !strings.Contains(out, want) {
+						t.Errorf("output = %q, want %q", out, want)
+					}
+				}
+
+				if tt.check != nil {
+					tt.check(t, env)
+				}
+			})
+		}
+	}
+}
+
+// The printed feed token opens the feed of its user only, and closes the
+// previous one.
+// Two resets in the same second, as a script runs them, keep both snapshots.
+func TestResetKeepsEverySnapshot(t *testing.T) {
+	cmds := NewCommands()
+
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			env := newTestEnv(t, backend.open)
+
+			for _, scope := range []string{"--posts", "--feeds"} {
+				_, err := captureStdout(t, func() error {
+					return cmds.Run(t.Context(), env.state, Command{Name: "reset", Args: []string{"reset", "--yes", scope}})
+				})
+				if err != nil {
+					t.Fatalf("reset %v: %v", scope, err)
+				}
+			}
+
+			paths, err := filepath.Glob(filepath.Join(env.dir, ".gator", "snapshots", "reset-*.jsonl.gz"))
+			if err != nil || len(paths) != 2 {
+				t.Errorf("snapshots = %v, %v, want one per reset", paths, err)
+			}
+		})
+	}
+}
+
+func TestFeedToken(t *testing.T) {
+	cmds := NewCommands()
+
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			env := newTestEnv(t, backend.open)
+			ctx := context.Background()
+
+			alice, _ := env.db.GetUserByName(ctx, "alice")
+			previous, err := env.state.Users.NewFeedToken(ctx, alice)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			out, err := captureStdout(t, func() error {
+				return cmds.Run(t.Context(), env.state, Command{Name: "feedtoken", Args: []string{"feedtoken"}})
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			token, ok := strings.CutPrefix(strings.Split(out, "\n")[0], "Feed token: ")
+			if !ok || !strings.Contains(out, "/users/alice/feed.rss?token="+token) {
+				t.Fatalf("output = %q, want the feed token and url", out)
+			}
+
+			user, err := env.state.Users.GetByFeedToken(ctx, "alice", token)
+			if err != nil || user.ID != alice.ID {
+				t.Errorf("user of the token = %v, %v, want alice", user.UserName, err)
+			}
+
+			for _, tt := range []struct{ userName, token string }{
+				{"alice", previous},
+				{"bob", token},
+				{"alice", ""},
+			} {
+				_, err := env.state.Users.GetByFeedToken(ctx, tt.userName, tt.token)
+				if !errors.Is(err, service.ErrUnauthorized) {
+					t.Errorf("feed of %v with token %q: error = %v, want unauthorized", tt.userName, tt.token, err)
+				}
+			}
+		})
+	}
+}
+
+// Admins without a password get a setup token, which sets their first
+// password once.
+func TestSetupToken(t *testing.T) {
+	cmds := NewCommands()
+
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			env := newTestEnv(t, backend.open)
+			ctx := context.Background()
+
+			mustUser(t, env.db, "dave", sql.NullString{}, true)
+			mustUser(t, env.db, "erin", sql.NullString{}, false)
+
+			tokens, err := env.state.Users.NewSetupTokens(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(tokens) != 1 || tokens[0].UserName != "dave" {
+				t.Fatalf("setup tokens = %+v, want one for dave", tokens)
+			}
+
+			setPassword := func(userName string) error {
+				typePasswords(t, "dave-password", "dave-password")
+				_, err := captureStdout(t, func() error {
+					return cmds.Run(t.Context(), env.state, Command{Name: "setpassword", Args: []string{"setpassword", "--token", tokens[0].Token, userName}})
+				})
+				return err
+			}
+
+			err = setPassword("erin")
+			if err == nil || !strings.Contains(err.Error(), "wrong setup token for user erin") {
+				t.Errorf("setting the password of erin with the token of dave: error = %v", err)
+			}
+
+			err = setPassword("dave")
+			if err != nil {
+				t.Fatal(err)
+			}
+			env.login(t, "dave")
+
+			err = setPassword("dave")
+			if err == nil || !strings.Contains(err.Error(), "wrong setup token") {
+				t.Errorf("reusing the setup token: error = %v", err)
+			}
+
+			tokens, err = env.state.Users.NewSetupTokens(ctx)
+			if err != nil || len(tokens) != 0 {
+				t.Errorf("setup tokens once dave has a password = %+v, %v, want none", tokens, err)
+			}
+		})
+	}
+}
+
+// A backup restored into a new database gives back every row as it was.
+func TestBackupRestore(t *testing.T) {
+	cmds := NewCommands()
+
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			env := newTestEnv(t, backend.open)
+			t.Chdir(env.dir)
+			ctx := context.Background()
+
+			alice, _ := env.db.GetUserByName(ctx, "alice")
+			err := env.db.MarkPostRead(ctx, database.MarkPostReadParams{UserID: alice.ID, PostID: goPostID, ReadAt: sql.NullTime{Time: time.Date(2024, 5, 3, 0, 0, 0, 0, time.UTC), Valid: true}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			blog, _ := env.db.GetFeedByURL(ctx, blogURL)
+			err = env.db.MarkFeedFailed(ctx, database.MarkFeedFailedParams{LastError: sql.NullString{String: "502", Valid: true}, ID: blog.ID})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = captureStdout(t, func() error {
+				return cmds.Run(t.Context(), env.state, Command{Name: "backup", Args: []string{"backup", "gator.jsonl.gz"}})
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// A new machine starts with an admin registered to run the restore.
+			db := backend.open(t, t.TempDir())
+			s := newTestState(t, db)
+			mustUser(t, db, "root", testPasswordHash(t, "root"), true)
+			_, token, err := s.Users.Login(ctx, "root", "root-password")
+			if err != nil {
+				t.Fatal(err)
+			}
+			s.Config.CurrentUserName = "root"
+			s.Config.SessionToken = token
+
+			_, err = captureStdout(t, func() error {
+				return cmds.Run(t.Context(), s, Command{Name: "restore", Args: []string{"restore", "--yes", "gator.jsonl.gz"}})
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			wantPost, _ := env.db.GetPostById(ctx, goPostID)
+			post, err := db.GetPostById(ctx, goPostID)
+			if err != nil || post.Title != wantPost.Title || post.Guid != wantPost.Guid || !post.PublishedAt.Equal(wantPost.PublishedAt) {
+				t.Errorf("restored post = %+v, %v, want %+v", post, err, wantPost)
+			}
+
+			feed, _ := db.GetFeedByURL(ctx, blogURL)
+			if feed.ID != blog.ID || feed.ConsecutiveFailures != 1 || feed.LastError.String != "502" {
+				t.Errorf("restored feed = %+v, want its health kept", feed)
+			}
+
+			states, _ := db.GetPostStates(ctx)
+			if len(states) != 1 || states[0].UserID != alice.ID || !states[0].ReadAt.Valid {
+				t.Errorf("restored post states = %+v", states)
+			}
+
+			// The full text index of SQLite is filled by the inserts too.
+			results, err := db.SearchPostsByUser(ctx, database.SearchPostsByUserParams{UserID: alice.ID, Query: "generics", Limit: 10})
+			if err != nil || len(results) != 1 {
+				t.Errorf("search after restore = %v, %v, want the Go post", results, err)
+			}
+		})
+	}
+}
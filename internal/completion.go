@@ -0,0 +1,374 @@
+package internal
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	conf "github.com/Alb3G/gator/internal/config"
+)
+
+// Handler of the completion command, it prints the completion script of a
+// shell built from the registered commands. Arguments completed from the
+// database call back into `gator __complete`.
+func (c *Commands) Completion(ctx context.Context, s *conf.State, cmd Command) error {
+	switch cmd.Args[1] {
+	case "bash":
+		c.writeBashCompletion(os.Stdout)
+	case "zsh":
+		c.writeZshCompletion(os.Stdout)
+	case "fish":
+		c.writeFishCompletion(os.Stdout)
+	default:
+		return fmt.Errorf("unknown shell %q, expected bash, zsh or fish", cmd.Args[1])
+	}
+
+	return nil
+}
+
+// Handler of the hidden __complete command. It gets the words of the command
+// line after gator, the last one being completed, and prints the matching
+// values one per line. Errors print nothing, the shell has nowhere to show
+// them.
+func (c *Commands) Complete(ctx context.Context, s *conf.State, cmd Command) error {
+	words := cmd.Args[1:]
+
+	spec, ok := c.AvailableCommands[words[0]]
+	if !ok || len(words) < 2 {
+		return nil
+	}
+
+	current := words[len(words)-1]
+
+	// Flags are skipped, the completion scripts complete them on their own.
+	position := 0
+	for _, word := range words[1 : len(words)-1] {
+		if !strings.HasPrefix(word, "-") {
+			position++
+		}
+	}
+
+	arg, ok := spec.argAt(position)
+	if !ok {
+		return nil
+	}
+
+	values := arg.Values
+	if arg.Complete != nil {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+
+		var err error
+		values, err = arg.Complete(ctx, s)
+		if err != nil {
+			return nil
+		}
+	}
+
+	for _, value := range values {
+		if strings.HasPrefix(value, current) {
+			fmt.Println(value)
+		}
+	}
+
+	return nil
+}
+
+// The argument at the given position, the variadic one takes every
+// position after it.
+func (spec Spec) argAt(position int) (Arg, bool) {
+	for i, arg := range spec.Args {
+		if i == position || (arg.Variadic && i < position) {
+			return arg, true
+		}
+	}
+
+	return Arg{}, false
+}
+
+func completeUserNames(ctx context.Context, s *conf.State) ([]string, error) {
+	users, err := s.Users.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(users))
+	for _, user := range users {
+		names = append(names, user.UserName)
+	}
+
+	return names, nil
+}
+
+// Urls of the feeds the current user doesn't follow yet, or of every feed
+// when nobody is logged in.
+func completeUnfollowedFeeds(ctx context.Context, s *conf.State) ([]string, error) {
+	feeds, err := s.Feeds.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	followed := map[string]bool{}
+	if user, err := s.Users.GetBySession(ctx, s.Config.SessionToken); err == nil {
+		follows, err := s.Feeds.Following(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+		for _, follow := range follows {
+			followed[follow.FeedUrl] = true
+		}
+	}
+
+	var urls []string
+	for _, feed := range feeds {
+		if !followed[feed.Url] {
+			urls = append(urls, feed.Url)
+		}
+	}
+
+	return urls, nil
+}
+
+func completeFollowedFeeds(ctx context.Context, s *conf.State) ([]string, error) {
+	user, err := s.Users.GetBySession(ctx, s.Config.SessionToken)
+	if err != nil {
+		return nil, err
+	}
+
+	follows, err := s.Feeds.Following(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	urls := make([]string, 0, len(follows))
+	for _, follow := range follows {
+		urls = append(urls, follow.FeedUrl)
+	}
+
+	return urls, nil
+}
+
+// What the completion scripts need to know about a command.
+type completionSpec struct {
+	Name        string
+	Description string
+	Flags       []*flag.Flag
+	// Fixed values of the first argument.
+	Values []string
+	// Some argument is completed by __complete.
+	Dynamic bool
+}
+
+func (c *Commands) completionSpecs() []completionSpec {
+	var specs []completionSpec
+
+	for _, name := range c.names() {
+		spec := c.AvailableCommands[name]
+		cs := completionSpec{Name: name, Description: spec.Description}
+
+		spec.flagSet().VisitAll(func(f *flag.Flag) {
+			cs.Flags = append(cs.Flags, f)
+		})
+
+		for i, arg := range spec.Args {
+			if i == 0 {
+				cs.Values = arg.Values
+			}
+			if arg.Complete != nil {
+				cs.Dynamic = true
+			}
+		}
+
+		specs = append(specs, cs)
+	}
+
+	return specs
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// Usage of a flag without the backquotes naming its value.
+func flagUsage(f *flag.Flag) string {
+	_, usage := flag.UnquoteUsage(f)
+	return usage
+}
+
+func flagNames(flags []*flag.Flag) string {
+	names := make([]string, len(flags))
+	for i, f := range flags {
+		names[i] = "--" + f.Name
+	}
+
+	return strings.Join(names, " ")
+}
+
+func (c *Commands) writeBashCompletion(w io.Writer) {
+	fmt.Fprint(w, `# bash completion for gator, load it with:
+#   source <(gator completion bash)
+
+_gator() {
+    local cur words cword
+    if declare -F _get_comp_words_by_ref >/dev/null; then
+        _get_comp_words_by_ref -n =: cur words cword
+    else
+        cur=${COMP_WORDS[COMP_CWORD]}
+        words=("${COMP_WORDS[@]}")
+        cword=$COMP_CWORD
+    fi
+
+    if [[ $cword -eq 1 ]]; then
+`)
+	fmt.Fprintf(w, "        COMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(c.names(), " "))
+	fmt.Fprint(w, `        return
+    fi
+
+    case ${words[1]} in
+`)
+
+	for _, spec := range c.completionSpecs() {
+		if len(spec.Flags) == 0 && spec.Values == nil && !spec.Dynamic {
+			continue
+		}
+
+		fmt.Fprintf(w, "    %v)\n", spec.Name)
+		if len(spec.Flags) > 0 {
+			fmt.Fprintf(w, "        if [[ $cur == -* ]]; then\n")
+			fmt.Fprintf(w, "            COMPREPLY=($(compgen -W %q -- \"$cur\"))\n", flagNames(spec.Flags))
+			fmt.Fprintf(w, "            return\n")
+			fmt.Fprintf(w, "        fi\n")
+		}
+		if spec.Values != nil {
+			fmt.Fprintf(w, "        [[ $cword -eq 2 ]] && COMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(spec.Values, " "))
+		}
+		if spec.Dynamic {
+			fmt.Fprintf(w, "        local IFS=$'\\n'\n")
+			fmt.Fprintf(w, "        COMPREPLY=($(gator __complete \"${words[@]:1:cword}\" 2>/dev/null))\n")
+		}
+		fmt.Fprintf(w, "        ;;\n")
+	}
+
+	fmt.Fprint(w, `    esac
+
+    if declare -F __ltrim_colon_completions >/dev/null; then
+        __ltrim_colon_completions "$cur"
+    fi
+}
+
+complete -o default -F _gator gator
+`)
+}
+
+// Quotes s for zsh and fish, inside single quotes.
+func singleQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// Entry of a zsh _describe array, colons separate the value from its
+// description.
+func zshDescribe(value, description string) string {
+	return singleQuote(strings.ReplaceAll(value, ":", `\:`) + ":" + description)
+}
+
+func (c *Commands) writeZshCompletion(w io.Writer) {
+	specs := c.completionSpecs()
+
+	fmt.Fprint(w, `#compdef gator
+# zsh completion for gator, load it with:
+#   source <(gator completion zsh)
+
+_gator() {
+    local -a commands
+    commands=(
+`)
+	for _, spec := range specs {
+		fmt.Fprintf(w, "        %v\n", zshDescribe(spec.Name, spec.Description))
+	}
+	fmt.Fprint(w, `    )
+
+    if (( CURRENT == 2 )); then
+        _describe 'command' commands
+        return
+    fi
+
+    local -a flags values
+    case ${words[2]} in
+`)
+
+	for _, spec := range specs {
+		if len(spec.Flags) == 0 && spec.Values == nil && !spec.Dynamic {
+			continue
+		}
+
+		fmt.Fprintf(w, "    %v)\n", spec.Name)
+		if len(spec.Flags) > 0 {
+			fmt.Fprintf(w, "        if [[ ${words[CURRENT]} == -* ]]; then\n")
+			fmt.Fprintf(w, "            flags=(\n")
+			for _, f := range spec.Flags {
+				fmt.Fprintf(w, "                %v\n", zshDescribe("--"+f.Name, flagUsage(f)))
+			}
+			fmt.Fprintf(w, "            )\n")
+			fmt.Fprintf(w, "            _describe 'flag' flags\n")
+			fmt.Fprintf(w, "            return\n")
+			fmt.Fprintf(w, "        fi\n")
+		}
+		if spec.Values != nil {
+			fmt.Fprintf(w, "        (( CURRENT == 3 )) && compadd -- %v\n", strings.Join(spec.Values, " "))
+		}
+		if spec.Dynamic {
+			fmt.Fprintf(w, "        values=(${(f)\"$(gator __complete \"${(@)words[2,CURRENT]}\" 2>/dev/null)\"})\n")
+			fmt.Fprintf(w, "        compadd -- $values\n")
+		}
+		if spec.Values == nil && !spec.Dynamic {
+			fmt.Fprintf(w, "        _files\n")
+		}
+		fmt.Fprintf(w, "        ;;\n")
+	}
+
+	fmt.Fprint(w, `    *)
+        _files
+        ;;
+    esac
+}
+
+if [[ $funcstack[1] == _gator ]]; then
+    _gator "$@"
+else
+    compdef _gator gator
+fi
+`)
+}
+
+func (c *Commands) writeFishCompletion(w io.Writer) {
+	fmt.Fprint(w, `# fish completion for gator, load it with:
+#   gator completion fish | source
+
+`)
+
+	for _, spec := range c.completionSpecs() {
+		fmt.Fprintf(w, "complete -c gator -n __fish_use_subcommand -f -a %v -d %v\n", spec.Name, singleQuote(spec.Description))
+
+		seen := singleQuote("__fish_seen_subcommand_from " + spec.Name)
+
+		for _, f := range spec.Flags {
+			required := " -r"
+			if isBoolFlag(f) {
+				required = ""
+			}
+			fmt.Fprintf(w, "complete -c gator -n %v -l %v%v -d %v\n", seen, f.Name, required, singleQuote(flagUsage(f)))
+		}
+		if spec.Values != nil {
+			first := singleQuote("__fish_seen_subcommand_from " + spec.Name + "; and test (count (commandline -opc)) -eq 2")
+			fmt.Fprintf(w, "complete -c gator -n %v -f -a %v\n", first, singleQuote(strings.Join(spec.Values, " ")))
+		}
+		if spec.Dynamic {
+			fmt.Fprintf(w, "complete -c gator -n %v -f -a '(gator __complete (commandline -opc)[2..] (commandline -ct | string collect --allow-empty) 2>/dev/null)'\n", seen)
+		}
+	}
+}
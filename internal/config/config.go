@@ -6,28 +6,49 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 
 	"github.com/Alb3G/gator/internal/database"
+	"github.com/Alb3G/gator/internal/migrate"
+	"github.com/Alb3G/gator/internal/service"
 )
 
 const CONFIG_FILE = ".gatorconfig.json"
 
 type State struct {
-	Config  *Config
-	Queries *database.Queries
+	Config     *Config
+	Queries    database.TxQuerier
+	Migrations *migrate.Migrator
+	Users      *service.UserService
+	Feeds      *service.FeedService
+	Posts      *service.PostService
+}
+
+func NewState(c *Config, queries database.TxQuerier) *State {
+	feeds := service.NewFeedService(queries)
+
+	return &State{
+		Config:  c,
+		Queries: queries,
+		Users:   service.NewUserService(queries),
+		Feeds:   feeds,
+		Posts:   service.NewPostService(queries, feeds),
+	}
 }
 
 type Config struct {
 	DbUrl           string `json:"db_url"`
 	CurrentUserName string `json:"current_user_name"`
+	// Token of the session opened by login, the user name is only shown.
+	SessionToken string `json:"session_token,omitempty"`
 }
 
-func (c *Config) SetUser(userName string) {
+// Stores the session of the user that just logged in.
+func (c *Config) SetSession(userName, token string) {
 	c.CurrentUserName = userName
+	c.SessionToken = token
 
 	c.write()
-
-	fmt.Println("New User has been set.")
 }
 
 func (c *Config) write() {
@@ -41,7 +62,14 @@ func (c *Config) write() {
 		log.Fatal(err)
 	}
 
-	err = os.WriteFile(path, newConfBytes, 0644)
+	// The session token is a secret, files written before it existed are
+	// made private as well.
+	err = os.WriteFile(path, newConfBytes, 0600)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = os.Chmod(path, 0600)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -67,6 +95,16 @@ func Read() *Config {
 	return &c
 }
 
+// Directory of the snapshots written before destructive commands.
+func SnapshotDir() (string, error) {
+	userHomeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(userHomeDir, ".gator", "snapshots"), nil
+}
+
 func getConfigFilePath() (string, error) {
 	userHomeDir, err := os.UserHomeDir()
 	if err != nil {
@@ -0,0 +1,10 @@
+package database
+
+import "errors"
+
+// Constraint violations reported by the Querier implementations that don't
+// use lib/pq. Postgres reports them as *pq.Error with SQLSTATE 23505 and 23503.
+var (
+	ErrUniqueViolation     = errors.New("unique constraint violation")
+	ErrForeignKeyViolation = errors.New("foreign key violation")
+)
@@ -7,6 +7,7 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
@@ -14,13 +15,13 @@ import (
 
 const createFeedFollow = `-- name: CreateFeedFollow :one
 WITH inserted_feed_follow AS (
-    INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id)
-	values ($1, $2, $3, $4, $5)
-    RETURNING id, created_at, updated_at, user_id, feed_id
+    INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id, category)
+	values ($1, $2, $3, $4, $5, $6)
+    RETURNING id, created_at, updated_at, user_id, feed_id, category
 )
 
 SELECT
-    inserted_feed_follow.id, inserted_feed_follow.created_at, inserted_feed_follow.updated_at, inserted_feed_follow.user_id, inserted_feed_follow.feed_id,
+    inserted_feed_follow.id, inserted_feed_follow.created_at, inserted_feed_follow.updated_at, inserted_feed_follow.user_id, inserted_feed_follow.feed_id, inserted_feed_follow.category,
     feeds.name AS feed_name,
     users.user_name AS user_name
 FROM inserted_feed_follow
@@ -36,6 +37,7 @@ type CreateFeedFollowParams struct {
 	UpdatedAt time.Time
 	UserID    uuid.UUID
 	FeedID    uuid.UUID
+	Category  sql.NullString
 }
 
 type CreateFeedFollowRow struct {
@@ -44,6 +46,7 @@ type CreateFeedFollowRow struct {
 	UpdatedAt time.Time
 	UserID    uuid.UUID
 	FeedID    uuid.UUID
+	Category  sql.NullString
 	FeedName  string
 	UserName  string
 }
@@ -55,6 +58,7 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 		arg.UpdatedAt,
 		arg.UserID,
 		arg.FeedID,
+		arg.Category,
 	)
 	var i CreateFeedFollowRow
 	err := row.Scan(
@@ -63,6 +67,7 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 		&i.UpdatedAt,
 		&i.UserID,
 		&i.FeedID,
+		&i.Category,
 		&i.FeedName,
 		&i.UserName,
 	)
@@ -83,10 +88,84 @@ func (q *Queries) DeleteFeedFollow(ctx context.Context, arg DeleteFeedFollowPara
 	return err
 }
 
+const getFeedFollows = `-- name: GetFeedFollows :many
+SELECT id, created_at, updated_at, user_id, feed_id, category FROM feed_follows
+`
+
+func (q *Queries) GetFeedFollows(ctx context.Context) ([]FeedFollow, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedFollows)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FeedFollow
+	for rows.Next() {
+		var i FeedFollow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.FeedID,
+			&i.Category,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFeedFollowsAfter = `-- name: GetFeedFollowsAfter :many
+SELECT id, created_at, updated_at, user_id, feed_id, category FROM feed_follows WHERE id > $1 ORDER BY id LIMIT $2
+`
+
+type GetFeedFollowsAfterParams struct {
+	AfterID uuid.UUID
+	Limit   int32
+}
+
+func (q *Queries) GetFeedFollowsAfter(ctx context.Context, arg GetFeedFollowsAfterParams) ([]FeedFollow, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedFollowsAfter, arg.AfterID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FeedFollow
+	for rows.Next() {
+		var i FeedFollow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.FeedID,
+			&i.Category,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getFeedFollowsByUser = `-- name: GetFeedFollowsByUser :many
 SELECT 
-    feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.user_id, feed_follows.feed_id, 
+    feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.user_id, feed_follows.feed_id, feed_follows.category, 
     feeds.name AS feed_name,
+    feeds.url AS feed_url,
     users.user_name AS user_name  
 FROM feed_follows
 INNER JOIN feeds ON feed_follows.feed_id = feeds.id
@@ -100,7 +179,9 @@ type GetFeedFollowsByUserRow struct {
 	UpdatedAt time.Time
 	UserID    uuid.UUID
 	FeedID    uuid.UUID
+	Category  sql.NullString
 	FeedName  string
+	FeedUrl   string
 	UserName  string
 }
 
@@ -119,7 +200,9 @@ func (q *Queries) GetFeedFollowsByUser(ctx context.Context, userID uuid.UUID) ([
 			&i.UpdatedAt,
 			&i.UserID,
 			&i.FeedID,
+			&i.Category,
 			&i.FeedName,
+			&i.FeedUrl,
 			&i.UserName,
 		); err != nil {
 			return nil, err
@@ -134,3 +217,29 @@ func (q *Queries) GetFeedFollowsByUser(ctx context.Context, userID uuid.UUID) ([
 	}
 	return items, nil
 }
+
+const restoreFeedFollow = `-- name: RestoreFeedFollow :exec
+INSERT INTO feed_follows(id, created_at, updated_at, user_id, feed_id, category)
+VALUES ($1, $2, $3, $4, $5, $6)
+`
+
+type RestoreFeedFollowParams struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	FeedID    uuid.UUID
+	Category  sql.NullString
+}
+
+func (q *Queries) RestoreFeedFollow(ctx context.Context, arg RestoreFeedFollowParams) error {
+	_, err := q.db.ExecContext(ctx, restoreFeedFollow,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.UserID,
+		arg.FeedID,
+		arg.Category,
+	)
+	return err
+}
@@ -15,7 +15,7 @@ import (
 
 const createFeed = `-- name: CreateFeed :one
 INSERT INTO feeds(id, created_at, updated_at, name, url, user_id) 
-VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
+VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, last_error, consecutive_failures, last_success_at, next_fetch_at
 `
 
 type CreateFeedParams struct {
@@ -45,12 +45,70 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.LastError,
+		&i.ConsecutiveFailures,
+		&i.LastSuccessAt,
+		&i.NextFetchAt,
 	)
 	return i, err
 }
 
+const deleteFeeds = `-- name: DeleteFeeds :exec
+DELETE FROM feeds
+`
+
+func (q *Queries) DeleteFeeds(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, deleteFeeds)
+	return err
+}
+
+const getFailingFeeds = `-- name: GetFailingFeeds :many
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, last_error, consecutive_failures, last_success_at, next_fetch_at FROM feeds
+WHERE consecutive_failures > 0
+ORDER BY consecutive_failures DESC, name ASC
+`
+
+func (q *Queries) GetFailingFeeds(ctx context.Context) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getFailingFeeds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.Etag,
+			&i.LastModified,
+			&i.LastError,
+			&i.ConsecutiveFailures,
+			&i.LastSuccessAt,
+			&i.NextFetchAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getFeedByURL = `-- name: GetFeedByURL :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds WHERE url = $1
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, last_error, consecutive_failures, last_success_at, next_fetch_at FROM feeds WHERE url = $1
 `
 
 func (q *Queries) GetFeedByURL(ctx context.Context, url string) (Feed, error) {
@@ -64,12 +122,18 @@ func (q *Queries) GetFeedByURL(ctx context.Context, url string) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.LastError,
+		&i.ConsecutiveFailures,
+		&i.LastSuccessAt,
+		&i.NextFetchAt,
 	)
 	return i, err
 }
 
 const getFeeds = `-- name: GetFeeds :many
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, last_error, consecutive_failures, last_success_at, next_fetch_at FROM feeds
 `
 
 func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
@@ -89,6 +153,58 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
 			&i.Url,
 			&i.UserID,
 			&i.LastFetchedAt,
+			&i.Etag,
+			&i.LastModified,
+			&i.LastError,
+			&i.ConsecutiveFailures,
+			&i.LastSuccessAt,
+			&i.NextFetchAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFeedsAfter = `-- name: GetFeedsAfter :many
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, last_error, consecutive_failures, last_success_at, next_fetch_at FROM feeds WHERE id > $1 ORDER BY id LIMIT $2
+`
+
+type GetFeedsAfterParams struct {
+	AfterID uuid.UUID
+	Limit   int32
+}
+
+func (q *Queries) GetFeedsAfter(ctx context.Context, arg GetFeedsAfterParams) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedsAfter, arg.AfterID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.Etag,
+			&i.LastModified,
+			&i.LastError,
+			&i.ConsecutiveFailures,
+			&i.LastSuccessAt,
+			&i.NextFetchAt,
 		); err != nil {
 			return nil, err
 		}
@@ -104,7 +220,7 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
 }
 
 const getNextFeedToFetch = `-- name: GetNextFeedToFetch :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds ORDER BY last_fetched_at ASC NULLS FIRST LIMIT 1
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, last_error, consecutive_failures, last_success_at, next_fetch_at FROM feeds ORDER BY last_fetched_at ASC NULLS FIRST LIMIT 1
 `
 
 func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
@@ -118,23 +234,158 @@ func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.LastError,
+		&i.ConsecutiveFailures,
+		&i.LastSuccessAt,
+		&i.NextFetchAt,
 	)
 	return i, err
 }
 
+const getNextFeedsToFetch = `-- name: GetNextFeedsToFetch :many
+UPDATE feeds
+SET last_fetched_at = $1, updated_at = $2
+WHERE id IN (
+    SELECT id FROM feeds
+    WHERE next_fetch_at IS NULL OR next_fetch_at <= $1
+    ORDER BY last_fetched_at ASC NULLS FIRST
+    LIMIT $3
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, last_error, consecutive_failures, last_success_at, next_fetch_at
+`
+
+type GetNextFeedsToFetchParams struct {
+	LastFetchedAt sql.NullTime
+	UpdatedAt     time.Time
+	Limit         int32
+}
+
+func (q *Queries) GetNextFeedsToFetch(ctx context.Context, arg GetNextFeedsToFetchParams) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getNextFeedsToFetch, arg.LastFetchedAt, arg.UpdatedAt, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.Etag,
+			&i.LastModified,
+			&i.LastError,
+			&i.ConsecutiveFailures,
+			&i.LastSuccessAt,
+			&i.NextFetchAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markFeedFailed = `-- name: MarkFeedFailed :exec
+UPDATE feeds
+SET last_error = $1, consecutive_failures = consecutive_failures + 1, next_fetch_at = $2, updated_at = $3
+WHERE id = $4
+`
+
+type MarkFeedFailedParams struct {
+	LastError   sql.NullString
+	NextFetchAt sql.NullTime
+	UpdatedAt   time.Time
+	ID          uuid.UUID
+}
+
+func (q *Queries) MarkFeedFailed(ctx context.Context, arg MarkFeedFailedParams) error {
+	_, err := q.db.ExecContext(ctx, markFeedFailed,
+		arg.LastError,
+		arg.NextFetchAt,
+		arg.UpdatedAt,
+		arg.ID,
+	)
+	return err
+}
+
 const markFeedFetched = `-- name: MarkFeedFetched :exec
 UPDATE feeds 
-SET last_fetched_at = $1, updated_at = $2
-WHERE id = $3
+SET last_fetched_at = $1, updated_at = $2, etag = $3, last_modified = $4,
+    last_success_at = $1, consecutive_failures = 0, last_error = NULL, next_fetch_at = NULL
+WHERE id = $5
 `
 
 type MarkFeedFetchedParams struct {
 	LastFetchedAt sql.NullTime
 	UpdatedAt     time.Time
+	Etag          sql.NullString
+	LastModified  sql.NullString
 	ID            uuid.UUID
 }
 
 func (q *Queries) MarkFeedFetched(ctx context.Context, arg MarkFeedFetchedParams) error {
-	_, err := q.db.ExecContext(ctx, markFeedFetched, arg.LastFetchedAt, arg.UpdatedAt, arg.ID)
+	_, err := q.db.ExecContext(ctx, markFeedFetched,
+		arg.LastFetchedAt,
+		arg.UpdatedAt,
+		arg.Etag,
+		arg.LastModified,
+		arg.ID,
+	)
+	return err
+}
+
+const restoreFeed = `-- name: RestoreFeed :exec
+INSERT INTO feeds(id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified,
+    last_error, consecutive_failures, last_success_at, next_fetch_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+`
+
+type RestoreFeedParams struct {
+	ID                  uuid.UUID
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	Name                string
+	Url                 string
+	UserID              uuid.UUID
+	LastFetchedAt       sql.NullTime
+	Etag                sql.NullString
+	LastModified        sql.NullString
+	LastError           sql.NullString
+	ConsecutiveFailures int32
+	LastSuccessAt       sql.NullTime
+	NextFetchAt         sql.NullTime
+}
+
+func (q *Queries) RestoreFeed(ctx context.Context, arg RestoreFeedParams) error {
+	_, err := q.db.ExecContext(ctx, restoreFeed,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.Name,
+		arg.Url,
+		arg.UserID,
+		arg.LastFetchedAt,
+		arg.Etag,
+		arg.LastModified,
+		arg.LastError,
+		arg.ConsecutiveFailures,
+		arg.LastSuccessAt,
+		arg.NextFetchAt,
+	)
 	return err
 }
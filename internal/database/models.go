@@ -12,13 +12,19 @@ import (
 )
 
 type Feed struct {
-	ID            uuid.UUID
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	Name          string
-	Url           string
-	UserID        uuid.UUID
-	LastFetchedAt sql.NullTime
+	ID                  uuid.UUID
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	Name                string
+	Url                 string
+	UserID              uuid.UUID
+	LastFetchedAt       sql.NullTime
+	Etag                sql.NullString
+	LastModified        sql.NullString
+	LastError           sql.NullString
+	ConsecutiveFailures int32
+	LastSuccessAt       sql.NullTime
+	NextFetchAt         sql.NullTime
 }
 
 type FeedFollow struct {
@@ -27,6 +33,13 @@ type FeedFollow struct {
 	UpdatedAt time.Time
 	UserID    uuid.UUID
 	FeedID    uuid.UUID
+	Category  sql.NullString
+}
+
+type FeedToken struct {
+	TokenHash string
+	UserID    uuid.UUID
+	CreatedAt time.Time
 }
 
 type Post struct {
@@ -38,11 +51,33 @@ type Post struct {
 	Description sql.NullString
 	PublishedAt time.Time
 	FeedID      uuid.UUID
+	Guid        sql.NullString
 }
 
-type User struct {
-	ID        uuid.UUID
+type PostState struct {
+	UserID    uuid.UUID
+	PostID    uuid.UUID
+	ReadAt    sql.NullTime
+	StarredAt sql.NullTime
+}
+
+type Session struct {
+	TokenHash string
+	UserID    uuid.UUID
 	CreatedAt time.Time
-	UpdatedAt time.Time
-	UserName  string
+}
+
+type SetupToken struct {
+	TokenHash string
+	UserID    uuid.UUID
+	CreatedAt time.Time
+}
+
+type User struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	UserName     string
+	PasswordHash sql.NullString
+	IsAdmin      bool
 }
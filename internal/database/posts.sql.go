@@ -15,7 +15,7 @@ import (
 
 const createPost = `-- name: CreatePost :one
 INSERT INTO posts(id, created_at, updated_at, title, url, description, published_at, feed_id) 
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, guid
 `
 
 type CreatePostParams struct {
@@ -50,26 +50,207 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		&i.Description,
 		&i.PublishedAt,
 		&i.FeedID,
+		&i.Guid,
 	)
 	return i, err
 }
 
+const deletePosts = `-- name: DeletePosts :exec
+DELETE FROM posts
+`
+
+func (q *Queries) DeletePosts(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, deletePosts)
+	return err
+}
+
+const getPostByGUID = `-- name: GetPostByGUID :one
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, guid FROM posts WHERE feed_id = $1 AND guid = $2
+`
+
+type GetPostByGUIDParams struct {
+	FeedID uuid.UUID
+	Guid   sql.NullString
+}
+
+func (q *Queries) GetPostByGUID(ctx context.Context, arg GetPostByGUIDParams) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPostByGUID, arg.FeedID, arg.Guid)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Guid,
+	)
+	return i, err
+}
+
+const getPostById = `-- name: GetPostById :one
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, guid FROM posts WHERE id = $1
+`
+
+func (q *Queries) GetPostById(ctx context.Context, id uuid.UUID) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPostById, id)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Guid,
+	)
+	return i, err
+}
+
+const getPosts = `-- name: GetPosts :many
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, guid FROM posts
+`
+
+func (q *Queries) GetPosts(ctx context.Context) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, getPosts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Post
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Guid,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostsAfter = `-- name: GetPostsAfter :many
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, guid FROM posts WHERE id > $1 ORDER BY id LIMIT $2
+`
+
+type GetPostsAfterParams struct {
+	AfterID uuid.UUID
+	Limit   int32
+}
+
+func (q *Queries) GetPostsAfter(ctx context.Context, arg GetPostsAfterParams) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsAfter, arg.AfterID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Post
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Guid,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getPostsByUser = `-- name: GetPostsByUser :many
-SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id FROM posts
-INNER JOIN feeds
-ON posts.feed_id = feeds.id
-WHERE feeds.user_id = $1
-ORDER BY posts.published_at DESC
-LIMIT $2
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, guid FROM (
+    SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.guid,
+        post_states.read_at, post_states.starred_at,
+        row_number() OVER (
+            PARTITION BY CASE WHEN posts.url = '' THEN posts.id::text ELSE posts.url END
+            ORDER BY posts.published_at DESC, posts.id DESC
+        ) AS copy_rank
+    FROM posts
+    INNER JOIN feed_follows
+    ON posts.feed_id = feed_follows.feed_id
+    LEFT JOIN post_states
+    ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+    WHERE feed_follows.user_id = $1
+        AND ($2::uuid IS NULL OR posts.feed_id = $2)
+        AND ($3::timestamp IS NULL OR posts.published_at >= $3)
+        AND ($4::timestamp IS NULL OR posts.published_at < $4)
+        AND ($5::text IS NULL OR feed_follows.category = $5)
+) AS timeline
+WHERE copy_rank = 1
+    AND (
+        $6::timestamp IS NULL
+        OR (published_at, id) < ($6, $7::uuid)
+    )
+    AND (NOT $8::bool OR read_at IS NULL)
+    AND (NOT $9::bool OR starred_at IS NOT NULL)
+ORDER BY published_at DESC, id DESC
+LIMIT $10 OFFSET $11
 `
 
 type GetPostsByUserParams struct {
-	UserID uuid.UUID
-	Limit  int32
+	UserID            uuid.UUID
+	FeedID            uuid.NullUUID
+	Since             sql.NullTime
+	Until             sql.NullTime
+	Category          sql.NullString
+	CursorPublishedAt sql.NullTime
+	CursorID          uuid.NullUUID
+	UnreadOnly        bool
+	StarredOnly       bool
+	Limit             int32
+	Offset            int32
 }
 
+// Posts of the feeds followed by the user, newest first. A link published by
+// several followed feeds is listed once, as its newest copy. Every filter is
+// optional, the cursor is the (published_at, id) of the last post seen.
 func (q *Queries) GetPostsByUser(ctx context.Context, arg GetPostsByUserParams) ([]Post, error) {
-	rows, err := q.db.QueryContext(ctx, getPostsByUser, arg.UserID, arg.Limit)
+	rows, err := q.db.QueryContext(ctx, getPostsByUser,
+		arg.UserID,
+		arg.FeedID,
+		arg.Since,
+		arg.Until,
+		arg.Category,
+		arg.CursorPublishedAt,
+		arg.CursorID,
+		arg.UnreadOnly,
+		arg.StarredOnly,
+		arg.Limit,
+		arg.Offset,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -86,6 +267,7 @@ func (q *Queries) GetPostsByUser(ctx context.Context, arg GetPostsByUserParams)
 			&i.Description,
 			&i.PublishedAt,
 			&i.FeedID,
+			&i.Guid,
 		); err != nil {
 			return nil, err
 		}
@@ -99,3 +281,187 @@ func (q *Queries) GetPostsByUser(ctx context.Context, arg GetPostsByUserParams)
 	}
 	return items, nil
 }
+
+const restorePost = `-- name: RestorePost :exec
+INSERT INTO posts(id, created_at, updated_at, title, url, description, published_at, feed_id, guid)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+`
+
+type RestorePostParams struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description sql.NullString
+	PublishedAt time.Time
+	FeedID      uuid.UUID
+	Guid        sql.NullString
+}
+
+func (q *Queries) RestorePost(ctx context.Context, arg RestorePostParams) error {
+	_, err := q.db.ExecContext(ctx, restorePost,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.Title,
+		arg.Url,
+		arg.Description,
+		arg.PublishedAt,
+		arg.FeedID,
+		arg.Guid,
+	)
+	return err
+}
+
+const searchPostsByUser = `-- name: SearchPostsByUser :many
+SELECT
+    posts.id,
+    posts.title,
+    posts.url,
+    posts.published_at,
+    feeds.name AS feed_name,
+    ts_rank(
+        setweight(to_tsvector('english', coalesce(posts.title, '')), 'A') ||
+        setweight(to_tsvector('english', coalesce(posts.description, '')), 'B'),
+        query
+    )::real AS rank,
+    ts_headline(
+        'english',
+        coalesce(posts.description, posts.title),
+        query,
+        'StartSel=<<, StopSel=>>, MaxWords=35, MinWords=15, MaxFragments=2'
+    ) AS snippet
+FROM posts
+INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+INNER JOIN feeds ON posts.feed_id = feeds.id
+CROSS JOIN websearch_to_tsquery('english', $1) AS query
+WHERE feed_follows.user_id = $2
+    AND (
+        setweight(to_tsvector('english', coalesce(posts.title, '')), 'A') ||
+        setweight(to_tsvector('english', coalesce(posts.description, '')), 'B')
+    ) @@ query
+ORDER BY rank DESC, posts.published_at DESC
+LIMIT $3
+`
+
+type SearchPostsByUserParams struct {
+	Query  string
+	UserID uuid.UUID
+	Limit  int32
+}
+
+type SearchPostsByUserRow struct {
+	ID          uuid.UUID
+	Title       string
+	Url         string
+	PublishedAt time.Time
+	FeedName    string
+	Rank        float32
+	Snippet     string
+}
+
+// Full text search over the posts of the feeds followed by the user, best
+// matches first. The snippet highlights the matches between << and >>. The
+// search vector is the expression of posts_search_idx, spelled the same so
+// the index is used.
+func (q *Queries) SearchPostsByUser(ctx context.Context, arg SearchPostsByUserParams) ([]SearchPostsByUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchPostsByUser, arg.Query, arg.UserID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchPostsByUserRow
+	for rows.Next() {
+		var i SearchPostsByUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Url,
+			&i.PublishedAt,
+			&i.FeedName,
+			&i.Rank,
+			&i.Snippet,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updatePost = `-- name: UpdatePost :exec
+UPDATE posts
+SET title = $1, url = $2, description = $3, updated_at = $4
+WHERE id = $5
+`
+
+type UpdatePostParams struct {
+	Title       string
+	Url         string
+	Description sql.NullString
+	UpdatedAt   time.Time
+	ID          uuid.UUID
+}
+
+func (q *Queries) UpdatePost(ctx context.Context, arg UpdatePostParams) error {
+	_, err := q.db.ExecContext(ctx, updatePost,
+		arg.Title,
+		arg.Url,
+		arg.Description,
+		arg.UpdatedAt,
+		arg.ID,
+	)
+	return err
+}
+
+const upsertPost = `-- name: UpsertPost :one
+INSERT INTO posts(id, created_at, updated_at, title, url, description, published_at, feed_id, guid)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+ON CONFLICT (feed_id, url) WHERE url <> '' DO UPDATE
+SET title = EXCLUDED.title,
+    description = EXCLUDED.description,
+    updated_at = EXCLUDED.updated_at,
+    guid = COALESCE(posts.guid, EXCLUDED.guid)
+WHERE posts.title IS DISTINCT FROM EXCLUDED.title
+    OR posts.description IS DISTINCT FROM EXCLUDED.description
+RETURNING (id = $1)::bool AS inserted
+`
+
+type UpsertPostParams struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description sql.NullString
+	PublishedAt time.Time
+	FeedID      uuid.UUID
+	Guid        sql.NullString
+}
+
+// Inserts a post or updates it when the feed already has one with the same
+// url and its content changed. No row is returned when the post was left
+// unchanged.
+func (q *Queries) UpsertPost(ctx context.Context, arg UpsertPostParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, upsertPost,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.Title,
+		arg.Url,
+		arg.Description,
+		arg.PublishedAt,
+		arg.FeedID,
+		arg.Guid,
+	)
+	var inserted bool
+	err := row.Scan(&inserted)
+	return inserted, err
+}
@@ -0,0 +1,143 @@
+// Package sqlite implements database.TxQuerier on top of SQLite with the pure
+// Go modernc.org/sqlite driver, for single user installs that don't want to
+// run a Postgres server. The queries mirror the ones in sql/queries.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/Alb3G/gator/internal/database"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+type DBTX interface {
+	ExecContext(context.Context, string, ...interface{}) (sql.Result, error)
+	PrepareContext(context.Context, string) (*sql.Stmt, error)
+	QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error)
+	QueryRowContext(context.Context, string, ...interface{}) *sql.Row
+}
+
+var _ database.TxQuerier = (*Queries)(nil)
+
+func New(db DBTX) *Queries {
+	return &Queries{db: db}
+}
+
+type Queries struct {
+	db DBTX
+}
+
+func (q *Queries) WithTx(tx *sql.Tx) *Queries {
+	return &Queries{
+		db: tx,
+	}
+}
+
+// Runs fn in a transaction, SQLite has a single connection so it also keeps
+// the other queries waiting until it commits or rolls back.
+func (q *Queries) InTx(ctx context.Context, fn func(database.TxQuerier) error) error {
+	db, ok := q.db.(*sql.DB)
+	if !ok {
+		return fn(q)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = fn(q.WithTx(tx))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Opens the database file at path, creating it if needed. Foreign keys are
+// enforced like in Postgres and a single connection is used, SQLite only
+// allows one writer at a time anyway.
+func Open(path string) (*sql.DB, error) {
+	params := url.Values{}
+	params.Add("_pragma", "foreign_keys(1)")
+	params.Add("_pragma", "busy_timeout(5000)")
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Set("_time_format", "sqlite")
+
+	db, err := sql.Open("sqlite", "file:"+path+"?"+params.Encode())
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+
+	return db, nil
+}
+
+// Times are stored as text, normalize them to UTC so they sort and compare
+// chronologically.
+func utc(t time.Time) time.Time {
+	return t.UTC()
+}
+
+func utcNull(t sql.NullTime) sql.NullTime {
+	if !t.Valid {
+		return t
+	}
+
+	return sql.NullTime{Time: t.Time.UTC(), Valid: true}
+}
+
+// Wraps the constraint violations in the driver independent errors of the
+// database package, any other error is returned as is.
+func wrapError(err error) error {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return err
+	}
+
+	switch sqliteErr.Code() {
+	case sqlite3.SQLITE_CONSTRAINT_UNIQUE, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
+		return fmt.Errorf("%w: %v", database.ErrUniqueViolation, err)
+	case sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY:
+		return fmt.Errorf("%w: %v", database.ErrForeignKeyViolation, err)
+	default:
+		return err
+	}
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// Runs a query returning many rows and scans each one with scan.
+func queryAll[T any](ctx context.Context, db DBTX, query string, scan func(scanner) (T, error), args ...any) ([]T, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []T
+	for rows.Next() {
+		item, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
@@ -0,0 +1,121 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/Alb3G/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+const createFeedFollow = `INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id, category)
+VALUES (?, ?, ?, ?, ?, ?)`
+
+// SQLite doesn't allow an INSERT inside a WITH clause, the names are read
+// with a second query.
+const getFeedFollowNames = `SELECT feeds.name, users.user_name
+FROM feed_follows
+INNER JOIN feeds ON feed_follows.feed_id = feeds.id
+INNER JOIN users ON feed_follows.user_id = users.id
+WHERE feed_follows.id = ?`
+
+func (q *Queries) CreateFeedFollow(ctx context.Context, arg database.CreateFeedFollowParams) (database.CreateFeedFollowRow, error) {
+	_, err := q.db.ExecContext(ctx, createFeedFollow,
+		arg.ID,
+		utc(arg.CreatedAt),
+		utc(arg.UpdatedAt),
+		arg.UserID,
+		arg.FeedID,
+		arg.Category,
+	)
+	if err != nil {
+		return database.CreateFeedFollowRow{}, wrapError(err)
+	}
+
+	i := database.CreateFeedFollowRow{
+		ID:        arg.ID,
+		CreatedAt: utc(arg.CreatedAt),
+		UpdatedAt: utc(arg.UpdatedAt),
+		UserID:    arg.UserID,
+		FeedID:    arg.FeedID,
+		Category:  arg.Category,
+	}
+	err = q.db.QueryRowContext(ctx, getFeedFollowNames, arg.ID).Scan(&i.FeedName, &i.UserName)
+	return i, err
+}
+
+const deleteFeedFollow = `DELETE FROM feed_follows WHERE user_id = ? AND feed_id = ?`
+
+func (q *Queries) DeleteFeedFollow(ctx context.Context, arg database.DeleteFeedFollowParams) error {
+	_, err := q.db.ExecContext(ctx, deleteFeedFollow, arg.UserID, arg.FeedID)
+	return err
+}
+
+const getFeedFollowsByUser = `SELECT
+    feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.user_id, feed_follows.feed_id, feed_follows.category,
+    feeds.name AS feed_name,
+    feeds.url AS feed_url,
+    users.user_name AS user_name
+FROM feed_follows
+INNER JOIN feeds ON feed_follows.feed_id = feeds.id
+INNER JOIN users ON feed_follows.user_id = users.id
+WHERE feed_follows.user_id = ?`
+
+func scanFeedFollowsByUserRow(row scanner) (database.GetFeedFollowsByUserRow, error) {
+	var i database.GetFeedFollowsByUserRow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.FeedID,
+		&i.Category,
+		&i.FeedName,
+		&i.FeedUrl,
+		&i.UserName,
+	)
+	return i, err
+}
+
+func (q *Queries) GetFeedFollowsByUser(ctx context.Context, userID uuid.UUID) ([]database.GetFeedFollowsByUserRow, error) {
+	return queryAll(ctx, q.db, getFeedFollowsByUser, scanFeedFollowsByUserRow, userID)
+}
+
+const getFeedFollows = `SELECT id, created_at, updated_at, user_id, feed_id, category FROM feed_follows`
+
+func scanFeedFollow(row scanner) (database.FeedFollow, error) {
+	var i database.FeedFollow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.FeedID,
+		&i.Category,
+	)
+	return i, err
+}
+
+func (q *Queries) GetFeedFollows(ctx context.Context) ([]database.FeedFollow, error) {
+	return queryAll(ctx, q.db, getFeedFollows, scanFeedFollow)
+}
+
+const getFeedFollowsAfter = `SELECT id, created_at, updated_at, user_id, feed_id, category FROM feed_follows WHERE id > ? ORDER BY id LIMIT ?`
+
+func (q *Queries) GetFeedFollowsAfter(ctx context.Context, arg database.GetFeedFollowsAfterParams) ([]database.FeedFollow, error) {
+	return queryAll(ctx, q.db, getFeedFollowsAfter, scanFeedFollow, arg.AfterID, arg.Limit)
+}
+
+const restoreFeedFollow = `INSERT INTO feed_follows(id, created_at, updated_at, user_id, feed_id, category)
+VALUES (?, ?, ?, ?, ?, ?)`
+
+func (q *Queries) RestoreFeedFollow(ctx context.Context, arg database.RestoreFeedFollowParams) error {
+	_, err := q.db.ExecContext(ctx, restoreFeedFollow,
+		arg.ID,
+		utc(arg.CreatedAt),
+		utc(arg.UpdatedAt),
+		arg.UserID,
+		arg.FeedID,
+		arg.Category,
+	)
+	return wrapError(err)
+}
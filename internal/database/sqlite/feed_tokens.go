@@ -0,0 +1,32 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/Alb3G/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+const createFeedToken = `INSERT INTO feed_tokens(token_hash, user_id, created_at)
+VALUES (?, ?, ?)`
+
+func (q *Queries) CreateFeedToken(ctx context.Context, arg database.CreateFeedTokenParams) error {
+	_, err := q.db.ExecContext(ctx, createFeedToken, arg.TokenHash, arg.UserID, utc(arg.CreatedAt))
+	return wrapError(err)
+}
+
+const deleteUserFeedTokens = `DELETE FROM feed_tokens WHERE user_id = ?`
+
+func (q *Queries) DeleteUserFeedTokens(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteUserFeedTokens, userID)
+	return err
+}
+
+const getUserByFeedToken = `SELECT users.id, users.created_at, users.updated_at, users.user_name, users.password_hash, users.is_admin
+FROM users
+JOIN feed_tokens ON feed_tokens.user_id = users.id
+WHERE feed_tokens.token_hash = ?`
+
+func (q *Queries) GetUserByFeedToken(ctx context.Context, tokenHash string) (database.User, error) {
+	return scanUser(q.db.QueryRowContext(ctx, getUserByFeedToken, tokenHash))
+}
@@ -0,0 +1,153 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/Alb3G/gator/internal/database"
+)
+
+const feedColumns = `id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, last_error, consecutive_failures, last_success_at, next_fetch_at`
+
+func scanFeed(row scanner) (database.Feed, error) {
+	var i database.Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.LastError,
+		&i.ConsecutiveFailures,
+		&i.LastSuccessAt,
+		&i.NextFetchAt,
+	)
+	return i, err
+}
+
+const createFeed = `INSERT INTO feeds(id, created_at, updated_at, name, url, user_id)
+VALUES (?, ?, ?, ?, ?, ?) RETURNING ` + feedColumns
+
+func (q *Queries) CreateFeed(ctx context.Context, arg database.CreateFeedParams) (database.Feed, error) {
+	row := q.db.QueryRowContext(ctx, createFeed,
+		arg.ID,
+		utc(arg.CreatedAt),
+		utc(arg.UpdatedAt),
+		arg.Name,
+		arg.Url,
+		arg.UserID,
+	)
+	feed, err := scanFeed(row)
+	return feed, wrapError(err)
+}
+
+const getFailingFeeds = `SELECT ` + feedColumns + ` FROM feeds
+WHERE consecutive_failures > 0
+ORDER BY consecutive_failures DESC, name ASC`
+
+func (q *Queries) GetFailingFeeds(ctx context.Context) ([]database.Feed, error) {
+	return queryAll(ctx, q.db, getFailingFeeds, scanFeed)
+}
+
+const getFeedByURL = `SELECT ` + feedColumns + ` FROM feeds WHERE url = ?`
+
+func (q *Queries) GetFeedByURL(ctx context.Context, url string) (database.Feed, error) {
+	return scanFeed(q.db.QueryRowContext(ctx, getFeedByURL, url))
+}
+
+const getFeeds = `SELECT ` + feedColumns + ` FROM feeds`
+
+func (q *Queries) GetFeeds(ctx context.Context) ([]database.Feed, error) {
+	return queryAll(ctx, q.db, getFeeds, scanFeed)
+}
+
+const getFeedsAfter = `SELECT ` + feedColumns + ` FROM feeds WHERE id > ? ORDER BY id LIMIT ?`
+
+func (q *Queries) GetFeedsAfter(ctx context.Context, arg database.GetFeedsAfterParams) ([]database.Feed, error) {
+	return queryAll(ctx, q.db, getFeedsAfter, scanFeed, arg.AfterID, arg.Limit)
+}
+
+const getNextFeedToFetch = `SELECT ` + feedColumns + ` FROM feeds ORDER BY last_fetched_at ASC NULLS FIRST LIMIT 1`
+
+func (q *Queries) GetNextFeedToFetch(ctx context.Context) (database.Feed, error) {
+	return scanFeed(q.db.QueryRowContext(ctx, getNextFeedToFetch))
+}
+
+// SQLite has no FOR UPDATE SKIP LOCKED, writes are serialized so the claim
+// is still atomic.
+const getNextFeedsToFetch = `UPDATE feeds
+SET last_fetched_at = ?1, updated_at = ?2
+WHERE id IN (
+    SELECT id FROM feeds
+    WHERE next_fetch_at IS NULL OR next_fetch_at <= ?1
+    ORDER BY last_fetched_at ASC NULLS FIRST
+    LIMIT ?3
+)
+RETURNING ` + feedColumns
+
+func (q *Queries) GetNextFeedsToFetch(ctx context.Context, arg database.GetNextFeedsToFetchParams) ([]database.Feed, error) {
+	return queryAll(ctx, q.db, getNextFeedsToFetch, scanFeed, utcNull(arg.LastFetchedAt), utc(arg.UpdatedAt), arg.Limit)
+}
+
+const markFeedFailed = `UPDATE feeds
+SET last_error = ?, consecutive_failures = consecutive_failures + 1, next_fetch_at = ?, updated_at = ?
+WHERE id = ?`
+
+func (q *Queries) MarkFeedFailed(ctx context.Context, arg database.MarkFeedFailedParams) error {
+	_, err := q.db.ExecContext(ctx, markFeedFailed,
+		arg.LastError,
+		utcNull(arg.NextFetchAt),
+		utc(arg.UpdatedAt),
+		arg.ID,
+	)
+	return err
+}
+
+const markFeedFetched = `UPDATE feeds
+SET last_fetched_at = ?1, updated_at = ?2, etag = ?3, last_modified = ?4,
+    last_success_at = ?1, consecutive_failures = 0, last_error = NULL, next_fetch_at = NULL
+WHERE id = ?5`
+
+func (q *Queries) MarkFeedFetched(ctx context.Context, arg database.MarkFeedFetchedParams) error {
+	_, err := q.db.ExecContext(ctx, markFeedFetched,
+		utcNull(arg.LastFetchedAt),
+		utc(arg.UpdatedAt),
+		arg.Etag,
+		arg.LastModified,
+		arg.ID,
+	)
+	return err
+}
+
+// Follows and posts are deleted in cascade.
+const deleteFeeds = `DELETE FROM feeds`
+
+func (q *Queries) DeleteFeeds(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, deleteFeeds)
+	return err
+}
+
+const restoreFeed = `INSERT INTO feeds(` + feedColumns + `)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+func (q *Queries) RestoreFeed(ctx context.Context, arg database.RestoreFeedParams) error {
+	_, err := q.db.ExecContext(ctx, restoreFeed,
+		arg.ID,
+		utc(arg.CreatedAt),
+		utc(arg.UpdatedAt),
+		arg.Name,
+		arg.Url,
+		arg.UserID,
+		utcNull(arg.LastFetchedAt),
+		arg.Etag,
+		arg.LastModified,
+		arg.LastError,
+		arg.ConsecutiveFailures,
+		utcNull(arg.LastSuccessAt),
+		utcNull(arg.NextFetchAt),
+	)
+	return wrapError(err)
+}
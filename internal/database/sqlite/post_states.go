@@ -0,0 +1,70 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/Alb3G/gator/internal/database"
+)
+
+const markPostRead = `INSERT INTO post_states(user_id, post_id, read_at)
+VALUES (?, ?, ?)
+ON CONFLICT (user_id, post_id) DO UPDATE SET read_at = excluded.read_at`
+
+func (q *Queries) MarkPostRead(ctx context.Context, arg database.MarkPostReadParams) error {
+	_, err := q.db.ExecContext(ctx, markPostRead, arg.UserID, arg.PostID, utcNull(arg.ReadAt))
+	return wrapError(err)
+}
+
+const markPostUnread = `UPDATE post_states SET read_at = NULL WHERE user_id = ? AND post_id = ?`
+
+func (q *Queries) MarkPostUnread(ctx context.Context, arg database.MarkPostUnreadParams) error {
+	_, err := q.db.ExecContext(ctx, markPostUnread, arg.UserID, arg.PostID)
+	return err
+}
+
+const restorePostState = `INSERT INTO post_states(user_id, post_id, read_at, starred_at)
+VALUES (?, ?, ?, ?)`
+
+func (q *Queries) RestorePostState(ctx context.Context, arg database.RestorePostStateParams) error {
+	_, err := q.db.ExecContext(ctx, restorePostState, arg.UserID, arg.PostID, utcNull(arg.ReadAt), utcNull(arg.StarredAt))
+	return wrapError(err)
+}
+
+const starPost = `INSERT INTO post_states(user_id, post_id, starred_at)
+VALUES (?, ?, ?)
+ON CONFLICT (user_id, post_id) DO UPDATE SET starred_at = excluded.starred_at`
+
+func (q *Queries) StarPost(ctx context.Context, arg database.StarPostParams) error {
+	_, err := q.db.ExecContext(ctx, starPost, arg.UserID, arg.PostID, utcNull(arg.StarredAt))
+	return wrapError(err)
+}
+
+const unstarPost = `UPDATE post_states SET starred_at = NULL WHERE user_id = ? AND post_id = ?`
+
+func (q *Queries) UnstarPost(ctx context.Context, arg database.UnstarPostParams) error {
+	_, err := q.db.ExecContext(ctx, unstarPost, arg.UserID, arg.PostID)
+	return err
+}
+
+const getPostStates = `SELECT user_id, post_id, read_at, starred_at FROM post_states`
+
+func scanPostState(row scanner) (database.PostState, error) {
+	var i database.PostState
+	err := row.Scan(
+		&i.UserID,
+		&i.PostID,
+		&i.ReadAt,
+		&i.StarredAt,
+	)
+	return i, err
+}
+
+func (q *Queries) GetPostStates(ctx context.Context) ([]database.PostState, error) {
+	return queryAll(ctx, q.db, getPostStates, scanPostState)
+}
+
+const getPostStatesAfter = `SELECT user_id, post_id, read_at, starred_at FROM post_states WHERE (user_id, post_id) > (?, ?) ORDER BY user_id, post_id LIMIT ?`
+
+func (q *Queries) GetPostStatesAfter(ctx context.Context, arg database.GetPostStatesAfterParams) ([]database.PostState, error) {
+	return queryAll(ctx, q.db, getPostStatesAfter, scanPostState, arg.AfterUserID, arg.AfterPostID, arg.Limit)
+}
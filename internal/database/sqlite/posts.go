@@ -0,0 +1,298 @@
+package sqlite
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Alb3G/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+// The columns of Post, the same list the Postgres queries select.
+const postColumns = `posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.guid`
+
+func scanPost(row scanner) (database.Post, error) {
+	var i database.Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Guid,
+	)
+	return i, err
+}
+
+const createPost = `INSERT INTO posts(id, created_at, updated_at, title, url, description, published_at, feed_id)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?) RETURNING ` + postColumns
+
+func (q *Queries) CreatePost(ctx context.Context, arg database.CreatePostParams) (database.Post, error) {
+	row := q.db.QueryRowContext(ctx, createPost,
+		arg.ID,
+		utc(arg.CreatedAt),
+		utc(arg.UpdatedAt),
+		arg.Title,
+		arg.Url,
+		arg.Description,
+		utc(arg.PublishedAt),
+		arg.FeedID,
+	)
+	post, err := scanPost(row)
+	return post, wrapError(err)
+}
+
+const getPostByGUID = `SELECT ` + postColumns + ` FROM posts WHERE feed_id = ? AND guid = ?`
+
+func (q *Queries) GetPostByGUID(ctx context.Context, arg database.GetPostByGUIDParams) (database.Post, error) {
+	return scanPost(q.db.QueryRowContext(ctx, getPostByGUID, arg.FeedID, arg.Guid))
+}
+
+const getPostById = `SELECT ` + postColumns + ` FROM posts WHERE id = ?`
+
+func (q *Queries) GetPostById(ctx context.Context, id uuid.UUID) (database.Post, error) {
+	return scanPost(q.db.QueryRowContext(ctx, getPostById, id))
+}
+
+const getPosts = `SELECT ` + postColumns + ` FROM posts`
+
+func (q *Queries) GetPosts(ctx context.Context) ([]database.Post, error) {
+	return queryAll(ctx, q.db, getPosts, scanPost)
+}
+
+const getPostsAfter = `SELECT ` + postColumns + ` FROM posts WHERE id > ? ORDER BY id LIMIT ?`
+
+func (q *Queries) GetPostsAfter(ctx context.Context, arg database.GetPostsAfterParams) ([]database.Post, error) {
+	return queryAll(ctx, q.db, getPostsAfter, scanPost, arg.AfterID, arg.Limit)
+}
+
+// Post states are deleted in cascade.
+const deletePosts = `DELETE FROM posts`
+
+func (q *Queries) DeletePosts(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, deletePosts)
+	return err
+}
+
+const getPostsByUser = `SELECT ` + postColumns + ` FROM (
+    SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.guid,
+        post_states.read_at, post_states.starred_at,
+        row_number() OVER (
+            PARTITION BY CASE WHEN posts.url = '' THEN posts.id ELSE posts.url END
+            ORDER BY posts.published_at DESC, posts.id DESC
+        ) AS copy_rank
+    FROM posts
+    INNER JOIN feed_follows
+    ON posts.feed_id = feed_follows.feed_id
+    LEFT JOIN post_states
+    ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+    WHERE feed_follows.user_id = ?1
+        AND (?2 IS NULL OR posts.feed_id = ?2)
+        AND (?3 IS NULL OR posts.published_at >= ?3)
+        AND (?4 IS NULL OR posts.published_at < ?4)
+        AND (?5 IS NULL OR feed_follows.category = ?5)
+) AS posts
+WHERE copy_rank = 1
+    AND (
+        ?6 IS NULL
+        OR (posts.published_at, posts.id) < (?6, ?7)
+    )
+    AND (NOT ?8 OR read_at IS NULL)
+    AND (NOT ?9 OR starred_at IS NOT NULL)
+ORDER BY posts.published_at DESC, posts.id DESC
+LIMIT ?10 OFFSET ?11`
+
+// Posts of the feeds followed by the user, newest first. A link published by
+// several followed feeds is listed once, as its newest copy. Every filter is
+// optional, the cursor is the (published_at, id) of the last post seen.
+func (q *Queries) GetPostsByUser(ctx context.Context, arg database.GetPostsByUserParams) ([]database.Post, error) {
+	return queryAll(ctx, q.db, getPostsByUser, scanPost,
+		arg.UserID,
+		arg.FeedID,
+		utcNull(arg.Since),
+		utcNull(arg.Until),
+		arg.Category,
+		utcNull(arg.CursorPublishedAt),
+		arg.CursorID,
+		arg.UnreadOnly,
+		arg.StarredOnly,
+		arg.Limit,
+		arg.Offset,
+	)
+}
+
+const restorePost = `INSERT INTO posts(id, created_at, updated_at, title, url, description, published_at, feed_id, guid)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+func (q *Queries) RestorePost(ctx context.Context, arg database.RestorePostParams) error {
+	_, err := q.db.ExecContext(ctx, restorePost,
+		arg.ID,
+		utc(arg.CreatedAt),
+		utc(arg.UpdatedAt),
+		arg.Title,
+		arg.Url,
+		arg.Description,
+		utc(arg.PublishedAt),
+		arg.FeedID,
+		arg.Guid,
+	)
+	return wrapError(err)
+}
+
+// bm25 ranks best matches lowest, it is negated to sort like ts_rank. The
+// title weighs more than the description, like the Postgres setweight, and
+// the snippet comes from the description when there is one, like the
+// coalesce given to ts_headline.
+const searchPostsByUser = `SELECT
+    posts.id,
+    posts.title,
+    posts.url,
+    posts.published_at,
+    feeds.name AS feed_name,
+    -bm25(posts_search, 10.0, 1.0) AS rank,
+    CASE WHEN posts.description IS NULL
+        THEN snippet(posts_search, 0, '<<', '>>', '...', 35)
+        ELSE snippet(posts_search, 1, '<<', '>>', '...', 35)
+    END AS snippet
+FROM posts_search
+INNER JOIN posts ON posts.seq = posts_search.rowid
+INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+INNER JOIN feeds ON posts.feed_id = feeds.id
+WHERE posts_search MATCH ?1
+    AND feed_follows.user_id = ?2
+ORDER BY rank DESC, posts.published_at DESC
+LIMIT ?3`
+
+func scanSearchRow(row scanner) (database.SearchPostsByUserRow, error) {
+	var i database.SearchPostsByUserRow
+	err := row.Scan(
+		&i.ID,
+		&i.Title,
+		&i.Url,
+		&i.PublishedAt,
+		&i.FeedName,
+		&i.Rank,
+		&i.Snippet,
+	)
+	return i, err
+}
+
+// Full text search over the posts of the feeds followed by the user, best
+// matches first. The snippet highlights the matches between << and >>.
+func (q *Queries) SearchPostsByUser(ctx context.Context, arg database.SearchPostsByUserParams) ([]database.SearchPostsByUserRow, error) {
+	query := matchQuery(arg.Query)
+	if query == "" {
+		return nil, nil
+	}
+
+	return queryAll(ctx, q.db, searchPostsByUser, scanSearchRow, query, arg.UserID, arg.Limit)
+}
+
+// Translates a web search style query, the syntax accepted by Postgres'
+// websearch_to_tsquery, to an FTS5 MATCH expression: "quoted phrases",
+// or between terms and -excluded terms. Every term is quoted so user input
+// can't produce an FTS5 syntax error.
+func matchQuery(query string) string {
+	var terms []string
+	negated := false
+
+	add := func(term string, phrase bool) {
+		if term == "" {
+			return
+		}
+		if !phrase && strings.EqualFold(term, "or") {
+			if len(terms) > 0 && terms[len(terms)-1] != "OR" {
+				terms = append(terms, "OR")
+			}
+			return
+		}
+
+		quoted := `"` + strings.ReplaceAll(term, `"`, `""`) + `"`
+		if negated {
+			negated = false
+			// FTS5 can only exclude terms from another match.
+			if len(terms) == 0 || terms[len(terms)-1] == "OR" {
+				return
+			}
+			quoted = "NOT " + quoted
+		}
+		terms = append(terms, quoted)
+	}
+
+	for query = strings.TrimSpace(query); query != ""; query = strings.TrimSpace(query) {
+		if strings.HasPrefix(query, "-") {
+			negated = true
+			query = query[1:]
+			continue
+		}
+
+		if rest, ok := strings.CutPrefix(query, `"`); ok {
+			phrase, after, _ := strings.Cut(rest, `"`)
+			add(strings.TrimSpace(phrase), true)
+			query = after
+			continue
+		}
+
+		end := strings.IndexAny(query, " \t\n\"")
+		if end < 0 {
+			end = len(query)
+		}
+		add(query[:end], false)
+		query = query[end:]
+	}
+
+	if len(terms) > 0 && terms[len(terms)-1] == "OR" {
+		terms = terms[:len(terms)-1]
+	}
+
+	return strings.Join(terms, " ")
+}
+
+const updatePost = `UPDATE posts
+SET title = ?, url = ?, description = ?, updated_at = ?
+WHERE id = ?`
+
+func (q *Queries) UpdatePost(ctx context.Context, arg database.UpdatePostParams) error {
+	_, err := q.db.ExecContext(ctx, updatePost,
+		arg.Title,
+		arg.Url,
+		arg.Description,
+		utc(arg.UpdatedAt),
+		arg.ID,
+	)
+	return wrapError(err)
+}
+
+const upsertPost = `INSERT INTO posts(id, created_at, updated_at, title, url, description, published_at, feed_id, guid)
+VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9)
+ON CONFLICT (feed_id, url) WHERE url <> '' DO UPDATE
+SET title = excluded.title,
+    description = excluded.description,
+    updated_at = excluded.updated_at,
+    guid = COALESCE(posts.guid, excluded.guid)
+WHERE posts.title IS NOT excluded.title
+    OR posts.description IS NOT excluded.description
+RETURNING id = ?1 AS inserted`
+
+// Inserts a post or updates it when the feed already has one with the same
+// url and its content changed. No row is returned when the post was left
+// unchanged.
+func (q *Queries) UpsertPost(ctx context.Context, arg database.UpsertPostParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, upsertPost,
+		arg.ID,
+		utc(arg.CreatedAt),
+		utc(arg.UpdatedAt),
+		arg.Title,
+		arg.Url,
+		arg.Description,
+		utc(arg.PublishedAt),
+		arg.FeedID,
+		arg.Guid,
+	)
+	var inserted bool
+	err := row.Scan(&inserted)
+	return inserted, wrapError(err)
+}
@@ -0,0 +1,496 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Alb3G/gator/internal/database"
+	"github.com/Alb3G/gator/internal/migrate"
+	"github.com/Alb3G/gator/sql/schema"
+	"github.com/google/uuid"
+)
+
+// Opens a migrated database in a temporary directory.
+func newTestDB(t *testing.T) (*sql.DB, *Queries) {
+	t.Helper()
+
+	db, err := Open(filepath.Join(t.TempDir(), "gator.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrator, err := migrate.New(db, schema.SQLiteMigrations(), migrate.SQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = migrator.Up(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return db, New(db)
+}
+
+func mustUser(t *testing.T, q *Queries, name string) database.User {
+	t.Helper()
+
+	user, err := q.CreateUser(context.Background(), database.CreateUserParams{ID: uuid.New(), CreatedAt: time.Now(), UpdatedAt: time.Now(), UserName: name})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func mustFeed(t *testing.T, q *Queries, user database.User, url string) database.Feed {
+	t.Helper()
+
+	feed, err := q.CreateFeed(context.Background(), database.CreateFeedParams{ID: uuid.New(), CreatedAt: time.Now(), UpdatedAt: time.Now(), Name: url, Url: url, UserID: user.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return feed
+}
+
+func TestMigrationsRoundTrip(t *testing.T) {
+	db, _ := newTestDB(t)
+	ctx := context.Background()
+
+	migrator, err := migrate.New(db, schema.SQLiteMigrations(), migrate.SQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	postgres, err := migrate.Load(schema.Migrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if migrator.Latest() != postgres[len(postgres)-1].Version {
+		t.Errorf("sqlite schema version %v, postgres %v", migrator.Latest(), postgres[len(postgres)-1].Version)
+	}
+
+	for {
+		_, ok, err := migrator.Down(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			break
+		}
+	}
+
+	version, err := migrator.Version(ctx)
+	if err != nil || version != 0 {
+		t.Fatalf("version after rolling everything back = %v, %v", version, err)
+	}
+
+	_, err = migrator.Up(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = migrator.Check(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Checking the schema of a new database leaves it untouched.
+func TestCheckIsReadOnly(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "gator.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+
+	migrator, err := migrate.New(db, schema.SQLiteMigrations(), migrate.SQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = migrator.Check(ctx)
+	if !errors.Is(err, migrate.ErrSchemaMismatch) || !strings.Contains(err.Error(), "at version 0") {
+		t.Fatalf("error = %v, want a mismatch at version 0", err)
+	}
+
+	var tables int
+	err = db.QueryRowContext(ctx, `SELECT count(*) FROM sqlite_master WHERE type = 'table'`).Scan(&tables)
+	if err != nil || tables != 0 {
+		t.Errorf("tables after the check = %v, %v, want none", tables, err)
+	}
+}
+
+func TestConstraintErrors(t *testing.T) {
+	_, q := newTestDB(t)
+	ctx := context.Background()
+
+	alice := mustUser(t, q, "alice")
+
+	_, err := q.CreateUser(ctx, database.CreateUserParams{ID: uuid.New(), UserName: "alice"})
+	if !errors.Is(err, database.ErrUniqueViolation) {
+		t.Errorf("duplicated user error = %v, want a unique violation", err)
+	}
+
+	_, err = q.CreateFeedFollow(ctx, database.CreateFeedFollowParams{ID: uuid.New(), UserID: alice.ID, FeedID: uuid.New()})
+	if !errors.Is(err, database.ErrForeignKeyViolation) {
+		t.Errorf("follow of a missing feed error = %v, want a foreign key violation", err)
+	}
+
+	_, err = q.GetUserByName(ctx, "bob")
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("missing user error = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestNextFeedsToFetch(t *testing.T) {
+	_, q := newTestDB(t)
+	ctx := context.Background()
+
+	alice := mustUser(t, q, "alice")
+	old := mustFeed(t, q, alice, "https://old.example.com")
+	never := mustFeed(t, q, alice, "https://never.example.com")
+	failing := mustFeed(t, q, alice, "https://failing.example.com")
+
+	now := time.Now()
+	q.MarkFeedFetched(ctx, database.MarkFeedFetchedParams{ID: old.ID, LastFetchedAt: sql.NullTime{Time: now.Add(-time.Hour), Valid: true}, UpdatedAt: now})
+	q.MarkFeedFailed(ctx, database.MarkFeedFailedParams{ID: failing.ID, NextFetchAt: sql.NullTime{Time: now.Add(time.Hour), Valid: true}, UpdatedAt: now})
+
+	// Never fetched feeds come first, like NULLS FIRST in Postgres.
+	next, err := q.GetNextFeedToFetch(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.ID != never.ID && next.ID != failing.ID {
+		t.Errorf("next feed = %v, want one never fetched", next.Url)
+	}
+
+	claimed, err := q.GetNextFeedsToFetch(ctx, database.GetNextFeedsToFetchParams{
+		LastFetchedAt: sql.NullTime{Time: now, Valid: true},
+		UpdatedAt:     now,
+		Limit:         10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := map[uuid.UUID]bool{}
+	for _, feed := range claimed {
+		got[feed.ID] = true
+		if !feed.LastFetchedAt.Time.Equal(now.UTC()) {
+			t.Errorf("claimed feed %v last fetched at %v, want %v", feed.Url, feed.LastFetchedAt.Time, now)
+		}
+	}
+	if len(claimed) != 2 || !got[old.ID] || !got[never.ID] {
+		t.Errorf("claimed %v feeds, want the old and never fetched ones but not the one backing off", len(claimed))
+	}
+
+	// The limit takes the least recently fetched feeds.
+	q.MarkFeedFetched(ctx, database.MarkFeedFetchedParams{ID: failing.ID, LastFetchedAt: sql.NullTime{Time: now.Add(-2 * time.Hour), Valid: true}, UpdatedAt: now})
+	claimed, err = q.GetNextFeedsToFetch(ctx, database.GetNextFeedsToFetchParams{
+		LastFetchedAt: sql.NullTime{Time: now.Add(time.Minute), Valid: true},
+		UpdatedAt:     now,
+		Limit:         1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(claimed) != 1 || claimed[0].ID != failing.ID {
+		t.Errorf("claimed %v, want the least recently fetched feed", claimed)
+	}
+}
+
+func TestPosts(t *testing.T) {
+	_, q := newTestDB(t)
+	ctx := context.Background()
+
+	alice := mustUser(t, q, "alice")
+	feed := mustFeed(t, q, alice, "https://blog.example.com")
+	_, err := q.CreateFeedFollow(ctx, database.CreateFeedFollowParams{ID: uuid.New(), UserID: alice.ID, FeedID: feed.ID, Category: sql.NullString{String: "tech", Valid: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Published at the same time, the id breaks the tie.
+	published := time.Date(2024, 5, 1, 12, 0, 0, 0, time.FixedZone("CEST", 2*3600))
+	ids := []uuid.UUID{
+		uuid.MustParse("00000000-0000-0000-0000-000000000001"),
+		uuid.MustParse("00000000-0000-0000-0000-000000000002"),
+		uuid.MustParse("00000000-0000-0000-0000-000000000003"),
+	}
+	titles := []string{"Go generics", "Rust traits", "Zig comptime"}
+
+	for i, id := range ids {
+		inserted, err := q.UpsertPost(ctx, database.UpsertPostParams{
+			ID:          id,
+			Title:       titles[i],
+			Url:         "https://blog.example.com/" + titles[i],
+			Description: sql.NullString{String: titles[i] + " explained", Valid: true},
+			PublishedAt: published,
+			FeedID:      feed.ID,
+			Guid:        sql.NullString{String: id.String(), Valid: true},
+		})
+		if err != nil || !inserted {
+			t.Fatalf("inserting %v = %v, %v", titles[i], inserted, err)
+		}
+	}
+
+	upsert := database.UpsertPostParams{ID: uuid.New(), Title: "Go generics", Url: "https://blog.example.com/Go generics", Description: sql.NullString{String: "Go generics explained", Valid: true}, PublishedAt: published, FeedID: feed.ID}
+	_, err = q.UpsertPost(ctx, upsert)
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("unchanged upsert error = %v, want sql.ErrNoRows", err)
+	}
+
+	upsert.Title = "Go generics, revisited"
+	inserted, err := q.UpsertPost(ctx, upsert)
+	if err != nil || inserted {
+		t.Errorf("changed upsert = %v, %v, want an update", inserted, err)
+	}
+
+	// Links are unique per feed, another feed may publish the same post, and
+	// posts without a link only need different guids.
+	other := mustFeed(t, q, alice, "https://planet.example.com")
+	shared := upsert
+	shared.ID, shared.FeedID = uuid.New(), other.ID
+	inserted, err = q.UpsertPost(ctx, shared)
+	if err != nil || !inserted {
+		t.Errorf("same url in another feed = %v, %v, want an insert", inserted, err)
+	}
+	for _, guid := range []string{"a", "b"} {
+		inserted, err = q.UpsertPost(ctx, database.UpsertPostParams{ID: uuid.New(), Title: guid, PublishedAt: published, FeedID: other.ID, Guid: sql.NullString{String: guid, Valid: true}})
+		if err != nil || !inserted {
+			t.Errorf("post %v without a link = %v, %v, want an insert", guid, inserted, err)
+		}
+	}
+
+	params := database.GetPostsByUserParams{UserID: alice.ID, Limit: 2, Category: sql.NullString{String: "tech", Valid: true}}
+	page, err := q.GetPostsByUser(ctx, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 2 || page[0].ID != ids[2] || page[1].ID != ids[1] {
+		t.Fatalf("first page = %v", page)
+	}
+	if !page[0].PublishedAt.Equal(published) {
+		t.Errorf("published at %v, want %v", page[0].PublishedAt, published)
+	}
+
+	params.CursorPublishedAt = sql.NullTime{Time: page[1].PublishedAt, Valid: true}
+	params.CursorID = uuid.NullUUID{UUID: page[1].ID, Valid: true}
+	page, err = q.GetPostsByUser(ctx, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 1 || page[0].ID != ids[0] || page[0].Title != "Go generics, revisited" {
+		t.Fatalf("second page = %v", page)
+	}
+
+	err = q.StarPost(ctx, database.StarPostParams{UserID: alice.ID, PostID: ids[1], StarredAt: sql.NullTime{Time: time.Now(), Valid: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = q.MarkPostRead(ctx, database.MarkPostReadParams{UserID: alice.ID, PostID: ids[2], ReadAt: sql.NullTime{Time: time.Now(), Valid: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	starred, _ := q.GetPostsByUser(ctx, database.GetPostsByUserParams{UserID: alice.ID, StarredOnly: true, Limit: 10})
+	unread, _ := q.GetPostsByUser(ctx, database.GetPostsByUserParams{UserID: alice.ID, UnreadOnly: true, Limit: 10})
+	since, _ := q.GetPostsByUser(ctx, database.GetPostsByUserParams{UserID: alice.ID, Since: sql.NullTime{Time: published.Add(time.Second), Valid: true}, Limit: 10})
+	if len(starred) != 1 || len(unread) != 2 || len(since) != 0 {
+		t.Errorf("got %v starred, %v unread and %v recent posts, want 1, 2 and 0", len(starred), len(unread), len(since))
+	}
+
+	results, err := q.SearchPostsByUser(ctx, database.SearchPostsByUserParams{Query: "revisited", UserID: alice.ID, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].ID != ids[0] || results[0].FeedName != feed.Name {
+		t.Fatalf("search results = %v", results)
+	}
+	if results[0].Snippet != "Go generics explained" {
+		t.Errorf("snippet = %q", results[0].Snippet)
+	}
+
+	err = q.Reset(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = q.GetPostById(ctx, ids[0])
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("post after reset error = %v, want sql.ErrNoRows", err)
+	}
+	results, _ = q.SearchPostsByUser(ctx, database.SearchPostsByUserParams{Query: "generics", UserID: alice.ID, Limit: 10})
+	if len(results) != 0 {
+		t.Errorf("search index still has %v results after reset", len(results))
+	}
+}
+
+// The posts table is rebuilt by migration 15, its posts, their states and the
+// search index must survive in both directions.
+// Backups page through post_states by its composite key, a page ending in the
+// middle of a user's states must carry on with the rest of them.
+func TestPostStatesAfter(t *testing.T) {
+	_, q := newTestDB(t)
+	ctx := context.Background()
+
+	alice := mustUser(t, q, "alice")
+	bob := mustUser(t, q, "bob")
+	feed := mustFeed(t, q, alice, "https://blog.example.com")
+
+	for i := range 3 {
+		post := uuid.New()
+		_, err := q.UpsertPost(ctx, database.UpsertPostParams{ID: post, Title: "Post", Url: "https://blog.example.com/" + post.String(), PublishedAt: time.Now(), FeedID: feed.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, user := range []database.User{alice, bob} {
+			err = q.MarkPostRead(ctx, database.MarkPostReadParams{UserID: user.ID, PostID: post, ReadAt: sql.NullTime{Time: time.Now(), Valid: true}})
+			if err != nil {
+				t.Fatalf("marking post %v read: %v", i, err)
+			}
+		}
+	}
+
+	var states []database.PostState
+	arg := database.GetPostStatesAfterParams{Limit: 2}
+	for {
+		page, err := q.GetPostStatesAfter(ctx, arg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page) == 0 {
+			break
+		}
+		states = append(states, page...)
+		last := page[len(page)-1]
+		arg.AfterUserID, arg.AfterPostID = last.UserID, last.PostID
+	}
+
+	if len(states) != 6 {
+		t.Fatalf("paged %v post states, want 6", len(states))
+	}
+	for i := 1; i < len(states); i++ {
+		prev, cur := states[i-1], states[i]
+		if prev.UserID.String() > cur.UserID.String() || prev.UserID == cur.UserID && prev.PostID.String() >= cur.PostID.String() {
+			t.Errorf("post states %v and %v out of key order", i-1, i)
+		}
+	}
+}
+
+func TestPostsRebuildKeepsData(t *testing.T) {
+	db, q := newTestDB(t)
+	ctx := context.Background()
+
+	alice := mustUser(t, q, "alice")
+	feed := mustFeed(t, q, alice, "https://blog.example.com")
+	_, err := q.CreateFeedFollow(ctx, database.CreateFeedFollowParams{ID: uuid.New(), UserID: alice.ID, FeedID: feed.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	post, err := q.CreatePost(ctx, database.CreatePostParams{ID: uuid.New(), Title: "Go generics", Url: "https://blog.example.com/go", PublishedAt: time.Now(), FeedID: feed.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = q.StarPost(ctx, database.StarPostParams{UserID: alice.ID, PostID: post.ID, StarredAt: sql.NullTime{Time: time.Now(), Valid: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	migrator, err := migrate.New(db, schema.SQLiteMigrations(), migrate.SQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, step := range []string{"down", "up"} {
+		if step == "down" {
+			_, _, err = migrator.Down(ctx)
+		} else {
+			_, err = migrator.Up(ctx)
+		}
+		if err != nil {
+			t.Fatalf("migrating %v: %v", step, err)
+		}
+
+		states, _ := q.GetPostStates(ctx)
+		if len(states) != 1 || states[0].PostID != post.ID {
+			t.Errorf("post states after migrating %v = %v", step, states)
+		}
+		results, err := q.SearchPostsByUser(ctx, database.SearchPostsByUserParams{Query: "generics", UserID: alice.ID, Limit: 10})
+		if err != nil || len(results) != 1 || results[0].ID != post.ID {
+			t.Errorf("search after migrating %v = %v, %v", step, results, err)
+		}
+	}
+}
+
+func TestMatchQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{query: "go generics", want: `"go" "generics"`},
+		{query: `"type parameters" go`, want: `"type parameters" "go"`},
+		{query: "go or rust", want: `"go" OR "rust"`},
+		{query: "go -rust", want: `"go" NOT "rust"`},
+		{query: "-rust", want: ``},
+		{query: `say "hi`, want: `"say" "hi"`},
+		{query: `c++ AND`, want: `"c++" "AND"`},
+		{query: "  ", want: ``},
+	}
+
+	for _, tt := range tests {
+		got := matchQuery(tt.query)
+		if got != tt.want {
+			t.Errorf("matchQuery(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestInTx(t *testing.T) {
+	_, q := newTestDB(t)
+	ctx := context.Background()
+
+	alice := mustUser(t, q, "alice")
+	errFailed := errors.New("failed")
+
+	err := q.InTx(ctx, func(tx database.TxQuerier) error {
+		mustFeed(t, tx.(*Queries), alice, "https://blog.example.com/rss")
+
+		// Nested calls join the transaction being rolled back.
+		return tx.InTx(ctx, func(tx database.TxQuerier) error {
+			mustFeed(t, tx.(*Queries), alice, "https://news.example.com/rss")
+			return errFailed
+		})
+	})
+	if !errors.Is(err, errFailed) {
+		t.Fatalf("InTx error = %v, want the error of fn", err)
+	}
+
+	feeds, err := q.GetFeeds(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(feeds) != 0 {
+		t.Errorf("feeds after rollback = %v, want none", len(feeds))
+	}
+
+	err = q.InTx(ctx, func(tx database.TxQuerier) error {
+		mustFeed(t, tx.(*Queries), alice, "https://blog.example.com/rss")
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	feeds, err = q.GetFeeds(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(feeds) != 1 {
+		t.Errorf("feeds after commit = %v, want 1", len(feeds))
+	}
+}
@@ -0,0 +1,32 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/Alb3G/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+const createSession = `INSERT INTO sessions(token_hash, user_id, created_at)
+VALUES (?, ?, ?)`
+
+func (q *Queries) CreateSession(ctx context.Context, arg database.CreateSessionParams) error {
+	_, err := q.db.ExecContext(ctx, createSession, arg.TokenHash, arg.UserID, utc(arg.CreatedAt))
+	return wrapError(err)
+}
+
+const deleteUserSessions = `DELETE FROM sessions WHERE user_id = ?`
+
+func (q *Queries) DeleteUserSessions(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteUserSessions, userID)
+	return err
+}
+
+const getUserBySession = `SELECT users.id, users.created_at, users.updated_at, users.user_name, users.password_hash, users.is_admin
+FROM users
+JOIN sessions ON sessions.user_id = users.id
+WHERE sessions.token_hash = ?`
+
+func (q *Queries) GetUserBySession(ctx context.Context, tokenHash string) (database.User, error) {
+	return scanUser(q.db.QueryRowContext(ctx, getUserBySession, tokenHash))
+}
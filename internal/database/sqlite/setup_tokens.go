@@ -0,0 +1,32 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/Alb3G/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+const createSetupToken = `INSERT INTO setup_tokens(token_hash, user_id, created_at)
+VALUES (?, ?, ?)`
+
+func (q *Queries) CreateSetupToken(ctx context.Context, arg database.CreateSetupTokenParams) error {
+	_, err := q.db.ExecContext(ctx, createSetupToken, arg.TokenHash, arg.UserID, utc(arg.CreatedAt))
+	return wrapError(err)
+}
+
+const deleteUserSetupTokens = `DELETE FROM setup_tokens WHERE user_id = ?`
+
+func (q *Queries) DeleteUserSetupTokens(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteUserSetupTokens, userID)
+	return err
+}
+
+const getUserBySetupToken = `SELECT users.id, users.created_at, users.updated_at, users.user_name, users.password_hash, users.is_admin
+FROM users
+JOIN setup_tokens ON setup_tokens.user_id = users.id
+WHERE setup_tokens.token_hash = ?`
+
+func (q *Queries) GetUserBySetupToken(ctx context.Context, tokenHash string) (database.User, error) {
+	return scanUser(q.db.QueryRowContext(ctx, getUserBySetupToken, tokenHash))
+}
@@ -0,0 +1,100 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/Alb3G/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+const userColumns = `id, created_at, updated_at, user_name, password_hash, is_admin`
+
+func scanUser(row scanner) (database.User, error) {
+	var i database.User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserName,
+		&i.PasswordHash,
+		&i.IsAdmin,
+	)
+	return i, err
+}
+
+const createUser = `INSERT INTO users(id, created_at, updated_at, user_name, password_hash, is_admin)
+VALUES (?, ?, ?, ?, ?, ?) RETURNING ` + userColumns
+
+func (q *Queries) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error) {
+	row := q.db.QueryRowContext(ctx, createUser,
+		arg.ID,
+		utc(arg.CreatedAt),
+		utc(arg.UpdatedAt),
+		arg.UserName,
+		arg.PasswordHash,
+		arg.IsAdmin,
+	)
+	user, err := scanUser(row)
+	return user, wrapError(err)
+}
+
+const deleteUser = `DELETE FROM users WHERE id = ?`
+
+func (q *Queries) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteUser, id)
+	return err
+}
+
+const getUserById = `SELECT ` + userColumns + ` FROM users WHERE id = ?`
+
+func (q *Queries) GetUserById(ctx context.Context, id uuid.UUID) (database.User, error) {
+	return scanUser(q.db.QueryRowContext(ctx, getUserById, id))
+}
+
+const getUserByName = `SELECT ` + userColumns + ` FROM users WHERE user_name = ?`
+
+func (q *Queries) GetUserByName(ctx context.Context, userName string) (database.User, error) {
+	return scanUser(q.db.QueryRowContext(ctx, getUserByName, userName))
+}
+
+const getUsers = `SELECT ` + userColumns + ` FROM users`
+
+func (q *Queries) GetUsers(ctx context.Context) ([]database.User, error) {
+	return queryAll(ctx, q.db, getUsers, scanUser)
+}
+
+const getUsersAfter = `SELECT ` + userColumns + ` FROM users WHERE id > ? ORDER BY id LIMIT ?`
+
+func (q *Queries) GetUsersAfter(ctx context.Context, arg database.GetUsersAfterParams) ([]database.User, error) {
+	return queryAll(ctx, q.db, getUsersAfter, scanUser, arg.AfterID, arg.Limit)
+}
+
+// Feeds, follows, posts and their states are deleted in cascade.
+const reset = `DELETE FROM users`
+
+func (q *Queries) Reset(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, reset)
+	return err
+}
+
+const restoreUser = `INSERT INTO users(id, created_at, updated_at, user_name, password_hash, is_admin)
+VALUES (?, ?, ?, ?, ?, ?)`
+
+func (q *Queries) RestoreUser(ctx context.Context, arg database.RestoreUserParams) error {
+	_, err := q.db.ExecContext(ctx, restoreUser,
+		arg.ID,
+		utc(arg.CreatedAt),
+		utc(arg.UpdatedAt),
+		arg.UserName,
+		arg.PasswordHash,
+		arg.IsAdmin,
+	)
+	return wrapError(err)
+}
+
+const setUserPassword = `UPDATE users SET password_hash = ?, updated_at = ? WHERE id = ?`
+
+func (q *Queries) SetUserPassword(ctx context.Context, arg database.SetUserPasswordParams) error {
+	_, err := q.db.ExecContext(ctx, setUserPassword, arg.PasswordHash, utc(arg.UpdatedAt), arg.ID)
+	return err
+}
@@ -0,0 +1,37 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+)
+
+// TxQuerier is a Querier able to run a group of queries atomically.
+type TxQuerier interface {
+	Querier
+	// Runs fn with a querier bound to a transaction, committed when fn
+	// returns nil and rolled back otherwise. Calling InTx on the querier
+	// passed to fn joins the same transaction.
+	InTx(ctx context.Context, fn func(TxQuerier) error) error
+}
+
+var _ TxQuerier = (*Queries)(nil)
+
+func (q *Queries) InTx(ctx context.Context, fn func(TxQuerier) error) error {
+	db, ok := q.db.(*sql.DB)
+	if !ok {
+		return fn(q)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = fn(q.WithTx(tx))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -7,21 +7,24 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 const createUser = `-- name: CreateUser :one
-INSERT INTO users(id, created_at, updated_at, user_name) 
-VALUES ($1, $2, $3, $4) RETURNING id, created_at, updated_at, user_name
+INSERT INTO users(id, created_at, updated_at, user_name, password_hash, is_admin) 
+VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at, updated_at, user_name, password_hash, is_admin
 `
 
 type CreateUserParams struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	UserName  string
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	UserName     string
+	PasswordHash sql.NullString
+	IsAdmin      bool
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
@@ -30,6 +33,8 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		arg.CreatedAt,
 		arg.UpdatedAt,
 		arg.UserName,
+		arg.PasswordHash,
+		arg.IsAdmin,
 	)
 	var i User
 	err := row.Scan(
@@ -37,12 +42,23 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.UserName,
+		&i.PasswordHash,
+		&i.IsAdmin,
 	)
 	return i, err
 }
 
+const deleteUser = `-- name: DeleteUser :exec
+DELETE FROM users WHERE id = $1
+`
+
+func (q *Queries) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteUser, id)
+	return err
+}
+
 const getUserById = `-- name: GetUserById :one
-SELECT id, created_at, updated_at, user_name FROM users where id = $1
+SELECT id, created_at, updated_at, user_name, password_hash, is_admin FROM users where id = $1
 `
 
 func (q *Queries) GetUserById(ctx context.Context, id uuid.UUID) (User, error) {
@@ -53,12 +69,14 @@ func (q *Queries) GetUserById(ctx context.Context, id uuid.UUID) (User, error) {
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.UserName,
+		&i.PasswordHash,
+		&i.IsAdmin,
 	)
 	return i, err
 }
 
 const getUserByName = `-- name: GetUserByName :one
-SELECT id, created_at, updated_at, user_name from users where user_name = $1
+SELECT id, created_at, updated_at, user_name, password_hash, is_admin from users where user_name = $1
 `
 
 func (q *Queries) GetUserByName(ctx context.Context, userName string) (User, error) {
@@ -69,12 +87,14 @@ func (q *Queries) GetUserByName(ctx context.Context, userName string) (User, err
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.UserName,
+		&i.PasswordHash,
+		&i.IsAdmin,
 	)
 	return i, err
 }
 
 const getUsers = `-- name: GetUsers :many
-SELECT id, created_at, updated_at, user_name FROM users
+SELECT id, created_at, updated_at, user_name, password_hash, is_admin FROM users
 `
 
 func (q *Queries) GetUsers(ctx context.Context) ([]User, error) {
@@ -91,6 +111,47 @@ func (q *Queries) GetUsers(ctx context.Context) ([]User, error) {
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.UserName,
+			&i.PasswordHash,
+			&i.IsAdmin,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUsersAfter = `-- name: GetUsersAfter :many
+SELECT id, created_at, updated_at, user_name, password_hash, is_admin FROM users WHERE id > $1 ORDER BY id LIMIT $2
+`
+
+type GetUsersAfterParams struct {
+	AfterID uuid.UUID
+	Limit   int32
+}
+
+func (q *Queries) GetUsersAfter(ctx context.Context, arg GetUsersAfterParams) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, getUsersAfter, arg.AfterID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserName,
+			&i.PasswordHash,
+			&i.IsAdmin,
 		); err != nil {
 			return nil, err
 		}
@@ -113,3 +174,44 @@ func (q *Queries) Reset(ctx context.Context) error {
 	_, err := q.db.ExecContext(ctx, reset)
 	return err
 }
+
+const restoreUser = `-- name: RestoreUser :exec
+INSERT INTO users(id, created_at, updated_at, user_name, password_hash, is_admin)
+VALUES ($1, $2, $3, $4, $5, $6)
+`
+
+type RestoreUserParams struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	UserName     string
+	PasswordHash sql.NullString
+	IsAdmin      bool
+}
+
+func (q *Queries) RestoreUser(ctx context.Context, arg RestoreUserParams) error {
+	_, err := q.db.ExecContext(ctx, restoreUser,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.UserName,
+		arg.PasswordHash,
+		arg.IsAdmin,
+	)
+	return err
+}
+
+const setUserPassword = `-- name: SetUserPassword :exec
+UPDATE users SET password_hash = $2, updated_at = $3 WHERE id = $1
+`
+
+type SetUserPasswordParams struct {
+	ID           uuid.UUID
+	PasswordHash sql.NullString
+	UpdatedAt    time.Time
+}
+
+func (q *Queries) SetUserPassword(ctx context.Context, arg SetUserPasswordParams) error {
+	_, err := q.db.ExecContext(ctx, setUserPassword, arg.ID, arg.PasswordHash, arg.UpdatedAt)
+	return err
+}
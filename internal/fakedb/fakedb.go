@@ -0,0 +1,1129 @@
+// Package fakedb is an in-memory implementation of database.TxQuerier used
+// by the tests. It mimics the constraints of the Postgres schema that the
+// services rely on: unique violations, missing rows, cascading deletes and
+// rolled back transactions.
+package fakedb
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Alb3G/gator/internal/database"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var _ database.TxQuerier = (*DB)(nil)
+
+type stateKey struct {
+	userID uuid.UUID
+	postID uuid.UUID
+}
+
+type DB struct {
+	// Serializes the transactions, mu guards every single query.
+	txMu    sync.Mutex
+	mu      sync.Mutex
+	users   []database.User
+	feeds   []database.Feed
+	follows []database.FeedFollow
+	posts   []database.Post
+	states  map[stateKey]database.PostState
+	// Sessions, feed and setup tokens by token hash.
+	sessions    map[string]database.Session
+	feedTokens  map[string]database.FeedToken
+	setupTokens map[string]database.SetupToken
+}
+
+func New() *DB {
+	return &DB{
+		states:      map[stateKey]database.PostState{},
+		sessions:    map[string]database.Session{},
+		feedTokens:  map[string]database.FeedToken{},
+		setupTokens: map[string]database.SetupToken{},
+	}
+}
+
+// Runs fn on the fake and restores its previous data when fn fails. Writes
+// made meanwhile outside the transaction are rolled back as well, the tests
+// don't mix them.
+func (db *DB) InTx(ctx context.Context, fn func(database.TxQuerier) error) error {
+	db.txMu.Lock()
+	defer db.txMu.Unlock()
+
+	saved := db.snapshot()
+
+	err := fn(tx{db})
+	if err != nil {
+		db.restore(saved)
+	}
+
+	return err
+}
+
+// The querier of a transaction, nested transactions join it.
+type tx struct {
+	*DB
+}
+
+func (t tx) InTx(ctx context.Context, fn func(database.TxQuerier) error) error {
+	return fn(t)
+}
+
+func (db *DB) snapshot() *DB {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	saved := &DB{
+		users:       append([]database.User(nil), db.users...),
+		feeds:       append([]database.Feed(nil), db.feeds...),
+		follows:     append([]database.FeedFollow(nil), db.follows...),
+		posts:       append([]database.Post(nil), db.posts...),
+		states:      make(map[stateKey]database.PostState, len(db.states)),
+		sessions:    make(map[string]database.Session, len(db.sessions)),
+		feedTokens:  make(map[string]database.FeedToken, len(db.feedTokens)),
+		setupTokens: make(map[string]database.SetupToken, len(db.setupTokens)),
+	}
+	for k, v := range db.states {
+		saved.states[k] = v
+	}
+	for k, v := range db.sessions {
+		saved.sessions[k] = v
+	}
+	for k, v := range db.feedTokens {
+		saved.feedTokens[k] = v
+	}
+	for k, v := range db.setupTokens {
+		saved.setupTokens[k] = v
+	}
+
+	return saved
+}
+
+func (db *DB) restore(saved *DB) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.users = saved.users
+	db.feeds = saved.feeds
+	db.follows = saved.follows
+	db.posts = saved.posts
+	db.states = saved.states
+	db.sessions = saved.sessions
+	db.feedTokens = saved.feedTokens
+	db.setupTokens = saved.setupTokens
+}
+
+func uniqueViolation(constraint string) error {
+	return &pq.Error{Code: "23505", Constraint: constraint}
+}
+
+func foreignKeyViolation(constraint string) error {
+	return &pq.Error{Code: "23503", Constraint: constraint}
+}
+
+// The rows with a key after the given one in key order, at most limit of
+// them, like the keyset pages read by the backups.
+func pageAfter[T any](rows []T, key func(T) string, after string, limit int32) []T {
+	var page []T
+	for _, row := range rows {
+		if key(row) > after {
+			page = append(page, row)
+		}
+	}
+
+	sort.Slice(page, func(i, j int) bool { return key(page[i]) < key(page[j]) })
+	if len(page) > int(limit) {
+		page = page[:limit]
+	}
+	return page
+}
+
+func (db *DB) userIndex(id uuid.UUID) int {
+	for i, u := range db.users {
+		if u.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (db *DB) feedIndex(id uuid.UUID) int {
+	for i, f := range db.feeds {
+		if f.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (db *DB) postIndex(id uuid.UUID) int {
+	for i, p := range db.posts {
+		if p.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (db *DB) followOf(userID, feedID uuid.UUID) (database.FeedFollow, bool) {
+	for _, ff := range db.follows {
+		if ff.UserID == userID && ff.FeedID == feedID {
+			return ff, true
+		}
+	}
+	return database.FeedFollow{}, false
+}
+
+// Users
+
+func (db *DB) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for _, u := range db.users {
+		if u.UserName == arg.UserName {
+			return database.User{}, uniqueViolation("users_user_name_key")
+		}
+	}
+	user := database.User{
+		ID:           arg.ID,
+		CreatedAt:    arg.CreatedAt,
+		UpdatedAt:    arg.UpdatedAt,
+		UserName:     arg.UserName,
+		PasswordHash: arg.PasswordHash,
+		IsAdmin:      arg.IsAdmin,
+	}
+	db.users = append(db.users, user)
+	return user, nil
+}
+
+func (db *DB) GetUserById(ctx context.Context, id uuid.UUID) (database.User, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	i := db.userIndex(id)
+	if i < 0 {
+		return database.User{}, sql.ErrNoRows
+	}
+	return db.users[i], nil
+}
+
+func (db *DB) GetUserByName(ctx context.Context, userName string) (database.User, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for _, u := range db.users {
+		if u.UserName == userName {
+			return u, nil
+		}
+	}
+	return database.User{}, sql.ErrNoRows
+}
+
+func (db *DB) GetUsers(ctx context.Context) ([]database.User, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	return append([]database.User(nil), db.users...), nil
+}
+
+func (db *DB) GetUsersAfter(ctx context.Context, arg database.GetUsersAfterParams) ([]database.User, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	return pageAfter(db.users, func(u database.User) string { return u.ID.String() }, arg.AfterID.String(), arg.Limit), nil
+}
+
+// Reset deletes every user, which cascades to everything else.
+func (db *DB) Reset(ctx context.Context) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.users = nil
+	db.feeds = nil
+	db.follows = nil
+	db.posts = nil
+	db.states = map[stateKey]database.PostState{}
+	db.sessions = map[string]database.Session{}
+	db.feedTokens = map[string]database.FeedToken{}
+	db.setupTokens = map[string]database.SetupToken{}
+	return nil
+}
+
+// DeleteUser deletes the user with their feeds, follows, post states,
+// sessions, feed and setup tokens, like the ON DELETE CASCADE constraints.
+func (db *DB) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.users = slices.DeleteFunc(db.users, func(u database.User) bool { return u.ID == id })
+	db.follows = slices.DeleteFunc(db.follows, func(ff database.FeedFollow) bool { return ff.UserID == id })
+	for k := range db.states {
+		if k.userID == id {
+			delete(db.states, k)
+		}
+	}
+	for k, session := range db.sessions {
+		if session.UserID == id {
+			delete(db.sessions, k)
+		}
+	}
+	for k, token := range db.feedTokens {
+		if token.UserID == id {
+			delete(db.feedTokens, k)
+		}
+	}
+	for k, token := range db.setupTokens {
+		if token.UserID == id {
+			delete(db.setupTokens, k)
+		}
+	}
+	db.deleteFeeds(func(f database.Feed) bool { return f.UserID == id })
+	return nil
+}
+
+func (db *DB) SetUserPassword(ctx context.Context, arg database.SetUserPasswordParams) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	i := db.userIndex(arg.ID)
+	if i < 0 {
+		return nil
+	}
+	db.users[i].PasswordHash = arg.PasswordHash
+	db.users[i].UpdatedAt = arg.UpdatedAt
+	return nil
+}
+
+func (db *DB) RestoreUser(ctx context.Context, arg database.RestoreUserParams) error {
+	_, err := db.CreateUser(ctx, database.CreateUserParams(arg))
+	return err
+}
+
+// Sessions
+
+func (db *DB) CreateSession(ctx context.Context, arg database.CreateSessionParams) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if db.userIndex(arg.UserID) < 0 {
+		return foreignKeyViolation("sessions_user_id_fkey")
+	}
+	if _, ok := db.sessions[arg.TokenHash]; ok {
+		return uniqueViolation("sessions_pkey")
+	}
+	db.sessions[arg.TokenHash] = database.Session(arg)
+	return nil
+}
+
+func (db *DB) GetUserBySession(ctx context.Context, tokenHash string) (database.User, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	session, ok := db.sessions[tokenHash]
+	if !ok {
+		return database.User{}, sql.ErrNoRows
+	}
+	return db.users[db.userIndex(session.UserID)], nil
+}
+
+func (db *DB) DeleteUserSessions(ctx context.Context, userID uuid.UUID) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for k, session := range db.sessions {
+		if session.UserID == userID {
+			delete(db.sessions, k)
+		}
+	}
+	return nil
+}
+
+// Feed tokens
+
+func (db *DB) CreateFeedToken(ctx context.Context, arg database.CreateFeedTokenParams) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if db.userIndex(arg.UserID) < 0 {
+		return foreignKeyViolation("feed_tokens_user_id_fkey")
+	}
+	if _, ok := db.feedTokens[arg.TokenHash]; ok {
+		return uniqueViolation("feed_tokens_pkey")
+	}
+	for _, token := range db.feedTokens {
+		if token.UserID == arg.UserID {
+			return uniqueViolation("feed_tokens_user_id_key")
+		}
+	}
+	db.feedTokens[arg.TokenHash] = database.FeedToken(arg)
+	return nil
+}
+
+func (db *DB) GetUserByFeedToken(ctx context.Context, tokenHash string) (database.User, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	token, ok := db.feedTokens[tokenHash]
+	if !ok {
+		return database.User{}, sql.ErrNoRows
+	}
+	return db.users[db.userIndex(token.UserID)], nil
+}
+
+func (db *DB) DeleteUserFeedTokens(ctx context.Context, userID uuid.UUID) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for k, token := range db.feedTokens {
+		if token.UserID == userID {
+			delete(db.feedTokens, k)
+		}
+	}
+	return nil
+}
+
+// Setup tokens
+
+func (db *DB) CreateSetupToken(ctx context.Context, arg database.CreateSetupTokenParams) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if db.userIndex(arg.UserID) < 0 {
+		return foreignKeyViolation("setup_tokens_user_id_fkey")
+	}
+	if _, ok := db.setupTokens[arg.TokenHash]; ok {
+		return uniqueViolation("setup_tokens_pkey")
+	}
+	for _, token := range db.setupTokens {
+		if token.UserID == arg.UserID {
+			return uniqueViolation("setup_tokens_user_id_key")
+		}
+	}
+	db.setupTokens[arg.TokenHash] = database.SetupToken(arg)
+	return nil
+}
+
+func (db *DB) GetUserBySetupToken(ctx context.Context, tokenHash string) (database.User, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	token, ok := db.setupTokens[tokenHash]
+	if !ok {
+		return database.User{}, sql.ErrNoRows
+	}
+	return db.users[db.userIndex(token.UserID)], nil
+}
+
+func (db *DB) DeleteUserSetupTokens(ctx context.Context, userID uuid.UUID) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for k, token := range db.setupTokens {
+		if token.UserID == userID {
+			delete(db.setupTokens, k)
+		}
+	}
+	return nil
+}
+
+// Feeds
+
+func (db *DB) CreateFeed(ctx context.Context, arg database.CreateFeedParams) (database.Feed, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if db.userIndex(arg.UserID) < 0 {
+		return database.Feed{}, foreignKeyViolation("feeds_user_id_fkey")
+	}
+	for _, f := range db.feeds {
+		if f.Url == arg.Url {
+			return database.Feed{}, uniqueViolation("feeds_url_key")
+		}
+	}
+	feed := database.Feed{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		Name:      arg.Name,
+		Url:       arg.Url,
+		UserID:    arg.UserID,
+	}
+	db.feeds = append(db.feeds, feed)
+	return feed, nil
+}
+
+func (db *DB) RestoreFeed(ctx context.Context, arg database.RestoreFeedParams) error {
+	_, err := db.CreateFeed(ctx, database.CreateFeedParams{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		Name:      arg.Name,
+		Url:       arg.Url,
+		UserID:    arg.UserID,
+	})
+	if err != nil {
+		return err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.feeds[len(db.feeds)-1] = database.Feed(arg)
+	return nil
+}
+
+func (db *DB) GetFailingFeeds(ctx context.Context) ([]database.Feed, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var feeds []database.Feed
+	for _, f := range db.feeds {
+		if f.ConsecutiveFailures > 0 {
+			feeds = append(feeds, f)
+		}
+	}
+	sort.SliceStable(feeds, func(i, j int) bool {
+		if feeds[i].ConsecutiveFailures != feeds[j].ConsecutiveFailures {
+			return feeds[i].ConsecutiveFailures > feeds[j].ConsecutiveFailures
+		}
+		return feeds[i].Name < feeds[j].Name
+	})
+	return feeds, nil
+}
+
+func (db *DB) GetFeedByURL(ctx context.Context, url string) (database.Feed, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for _, f := range db.feeds {
+		if f.Url == url {
+			return f, nil
+		}
+	}
+	return database.Feed{}, sql.ErrNoRows
+}
+
+func (db *DB) GetFeeds(ctx context.Context) ([]database.Feed, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	return append([]database.Feed(nil), db.feeds...), nil
+}
+
+func (db *DB) GetFeedsAfter(ctx context.Context, arg database.GetFeedsAfterParams) ([]database.Feed, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	return pageAfter(db.feeds, func(f database.Feed) string { return f.ID.String() }, arg.AfterID.String(), arg.Limit), nil
+}
+
+// DeleteFeeds deletes every feed, which cascades to the follows and posts.
+func (db *DB) DeleteFeeds(ctx context.Context) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.deleteFeeds(func(database.Feed) bool { return true })
+	return nil
+}
+
+// deleteFeeds deletes the matching feeds with their follows and posts.
+func (db *DB) deleteFeeds(match func(database.Feed) bool) {
+	deleted := map[uuid.UUID]bool{}
+	db.feeds = slices.DeleteFunc(db.feeds, func(f database.Feed) bool {
+		deleted[f.ID] = match(f)
+		return deleted[f.ID]
+	})
+	db.follows = slices.DeleteFunc(db.follows, func(ff database.FeedFollow) bool { return deleted[ff.FeedID] })
+	db.deletePosts(func(p database.Post) bool { return deleted[p.FeedID] })
+}
+
+// feedsByLastFetched returns the feeds never fetched first, then the ones
+// fetched the longest time ago.
+func (db *DB) feedsByLastFetched() []int {
+	order := make([]int, len(db.feeds))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := db.feeds[order[i]].LastFetchedAt, db.feeds[order[j]].LastFetchedAt
+		if !a.Valid || !b.Valid {
+			return !a.Valid && b.Valid
+		}
+		return a.Time.Before(b.Time)
+	})
+	return order
+}
+
+func (db *DB) GetNextFeedToFetch(ctx context.Context) (database.Feed, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	order := db.feedsByLastFetched()
+	if len(order) == 0 {
+		return database.Feed{}, sql.ErrNoRows
+	}
+	return db.feeds[order[0]], nil
+}
+
+func (db *DB) GetNextFeedsToFetch(ctx context.Context, arg database.GetNextFeedsToFetchParams) ([]database.Feed, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var feeds []database.Feed
+	for _, i := range db.feedsByLastFetched() {
+		if int32(len(feeds)) >= arg.Limit {
+			break
+		}
+		next := db.feeds[i].NextFetchAt
+		if next.Valid && next.Time.After(arg.LastFetchedAt.Time) {
+			continue
+		}
+		db.feeds[i].LastFetchedAt = arg.LastFetchedAt
+		db.feeds[i].UpdatedAt = arg.UpdatedAt
+		feeds = append(feeds, db.feeds[i])
+	}
+	return feeds, nil
+}
+
+func (db *DB) MarkFeedFailed(ctx context.Context, arg database.MarkFeedFailedParams) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	i := db.feedIndex(arg.ID)
+	if i < 0 {
+		return nil
+	}
+	db.feeds[i].LastError = arg.LastError
+	db.feeds[i].ConsecutiveFailures++
+	db.feeds[i].NextFetchAt = arg.NextFetchAt
+	db.feeds[i].UpdatedAt = arg.UpdatedAt
+	return nil
+}
+
+func (db *DB) MarkFeedFetched(ctx context.Context, arg database.MarkFeedFetchedParams) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	i := db.feedIndex(arg.ID)
+	if i < 0 {
+		return nil
+	}
+	db.feeds[i].LastFetchedAt = arg.LastFetchedAt
+	db.feeds[i].UpdatedAt = arg.UpdatedAt
+	db.feeds[i].Etag = arg.Etag
+	db.feeds[i].LastModified = arg.LastModified
+	db.feeds[i].LastSuccessAt = arg.LastFetchedAt
+	db.feeds[i].ConsecutiveFailures = 0
+	db.feeds[i].LastError = sql.NullString{}
+	db.feeds[i].NextFetchAt = sql.NullTime{}
+	return nil
+}
+
+// Feed follows
+
+func (db *DB) CreateFeedFollow(ctx context.Context, arg database.CreateFeedFollowParams) (database.CreateFeedFollowRow, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	u := db.userIndex(arg.UserID)
+	f := db.feedIndex(arg.FeedID)
+	if u < 0 || f < 0 {
+		return database.CreateFeedFollowRow{}, foreignKeyViolation("feed_follows_feed_id_fkey")
+	}
+	if _, ok := db.followOf(arg.UserID, arg.FeedID); ok {
+		return database.CreateFeedFollowRow{}, uniqueViolation("feed_follows_user_id_feed_id_key")
+	}
+	db.follows = append(db.follows, database.FeedFollow{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		UserID:    arg.UserID,
+		FeedID:    arg.FeedID,
+		Category:  arg.Category,
+	})
+	return database.CreateFeedFollowRow{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		UserID:    arg.UserID,
+		FeedID:    arg.FeedID,
+		Category:  arg.Category,
+		FeedName:  db.feeds[f].Name,
+		UserName:  db.users[u].UserName,
+	}, nil
+}
+
+func (db *DB) DeleteFeedFollow(ctx context.Context, arg database.DeleteFeedFollowParams) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	follows := db.follows[:0]
+	for _, ff := range db.follows {
+		if ff.UserID != arg.UserID || ff.FeedID != arg.FeedID {
+			follows = append(follows, ff)
+		}
+	}
+	db.follows = follows
+	return nil
+}
+
+func (db *DB) GetFeedFollows(ctx context.Context) ([]database.FeedFollow, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	return append([]database.FeedFollow(nil), db.follows...), nil
+}
+
+func (db *DB) GetFeedFollowsAfter(ctx context.Context, arg database.GetFeedFollowsAfterParams) ([]database.FeedFollow, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	return pageAfter(db.follows, func(ff database.FeedFollow) string { return ff.ID.String() }, arg.AfterID.String(), arg.Limit), nil
+}
+
+func (db *DB) GetFeedFollowsByUser(ctx context.Context, userID uuid.UUID) ([]database.GetFeedFollowsByUserRow, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var rows []database.GetFeedFollowsByUserRow
+	for _, ff := range db.follows {
+		if ff.UserID != userID {
+			continue
+		}
+		feed := db.feeds[db.feedIndex(ff.FeedID)]
+		user := db.users[db.userIndex(ff.UserID)]
+		rows = append(rows, database.GetFeedFollowsByUserRow{
+			ID:        ff.ID,
+			CreatedAt: ff.CreatedAt,
+			UpdatedAt: ff.UpdatedAt,
+			UserID:    ff.UserID,
+			FeedID:    ff.FeedID,
+			Category:  ff.Category,
+			FeedName:  feed.Name,
+			FeedUrl:   feed.Url,
+			UserName:  user.UserName,
+		})
+	}
+	return rows, nil
+}
+
+func (db *DB) RestoreFeedFollow(ctx context.Context, arg database.RestoreFeedFollowParams) error {
+	_, err := db.CreateFeedFollow(ctx, database.CreateFeedFollowParams(arg))
+	return err
+}
+
+// Posts
+
+func (db *DB) CreatePost(ctx context.Context, arg database.CreatePostParams) (database.Post, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if db.feedIndex(arg.FeedID) < 0 {
+		return database.Post{}, foreignKeyViolation("posts_feed_id_fkey")
+	}
+	for _, p := range db.posts {
+		if p.FeedID == arg.FeedID && p.Url != "" && p.Url == arg.Url {
+			return database.Post{}, uniqueViolation("posts_feed_id_url_idx")
+		}
+	}
+	post := database.Post{
+		ID:          arg.ID,
+		CreatedAt:   arg.CreatedAt,
+		UpdatedAt:   arg.UpdatedAt,
+		Title:       arg.Title,
+		Url:         arg.Url,
+		Description: arg.Description,
+		PublishedAt: arg.PublishedAt,
+		FeedID:      arg.FeedID,
+	}
+	db.posts = append(db.posts, post)
+	return post, nil
+}
+
+func (db *DB) RestorePost(ctx context.Context, arg database.RestorePostParams) error {
+	_, err := db.CreatePost(ctx, database.CreatePostParams{
+		ID:          arg.ID,
+		CreatedAt:   arg.CreatedAt,
+		UpdatedAt:   arg.UpdatedAt,
+		Title:       arg.Title,
+		Url:         arg.Url,
+		Description: arg.Description,
+		PublishedAt: arg.PublishedAt,
+		FeedID:      arg.FeedID,
+	})
+	if err != nil {
+		return err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.posts[len(db.posts)-1].Guid = arg.Guid
+	return nil
+}
+
+// DeletePosts deletes every post, which cascades to their states.
+func (db *DB) DeletePosts(ctx context.Context) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.deletePosts(func(database.Post) bool { return true })
+	return nil
+}
+
+// deletePosts deletes the matching posts with their states.
+func (db *DB) deletePosts(match func(database.Post) bool) {
+	deleted := map[uuid.UUID]bool{}
+	db.posts = slices.DeleteFunc(db.posts, func(p database.Post) bool {
+		deleted[p.ID] = match(p)
+		return deleted[p.ID]
+	})
+	for k := range db.states {
+		if deleted[k.postID] {
+			delete(db.states, k)
+		}
+	}
+}
+
+func (db *DB) GetPosts(ctx context.Context) ([]database.Post, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	return append([]database.Post(nil), db.posts...), nil
+}
+
+func (db *DB) GetPostsAfter(ctx context.Context, arg database.GetPostsAfterParams) ([]database.Post, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	return pageAfter(db.posts, func(p database.Post) string { return p.ID.String() }, arg.AfterID.String(), arg.Limit), nil
+}
+
+func (db *DB) GetPostByGUID(ctx context.Context, arg database.GetPostByGUIDParams) (database.Post, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for _, p := range db.posts {
+		if p.FeedID == arg.FeedID && p.Guid.Valid && arg.Guid.Valid && p.Guid.String == arg.Guid.String {
+			return p, nil
+		}
+	}
+	return database.Post{}, sql.ErrNoRows
+}
+
+func (db *DB) GetPostById(ctx context.Context, id uuid.UUID) (database.Post, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	i := db.postIndex(id)
+	if i < 0 {
+		return database.Post{}, sql.ErrNoRows
+	}
+	return db.posts[i], nil
+}
+
+// postsByDate sorts posts newest first, breaking ties by id like the
+// (published_at, id) keyset used for pagination.
+func postsByDate(posts []database.Post) {
+	sort.SliceStable(posts, func(i, j int) bool {
+		if !posts[i].PublishedAt.Equal(posts[j].PublishedAt) {
+			return posts[i].PublishedAt.After(posts[j].PublishedAt)
+		}
+		return posts[i].ID.String() > posts[j].ID.String()
+	})
+}
+
+func (db *DB) GetPostsByUser(ctx context.Context, arg database.GetPostsByUserParams) ([]database.Post, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var timeline []database.Post
+	for _, p := range db.posts {
+		ff, ok := db.followOf(arg.UserID, p.FeedID)
+		if !ok {
+			continue
+		}
+		if arg.FeedID.Valid && p.FeedID != arg.FeedID.UUID {
+			continue
+		}
+		if arg.Since.Valid && p.PublishedAt.Before(arg.Since.Time) {
+			continue
+		}
+		if arg.Until.Valid && !p.PublishedAt.Before(arg.Until.Time) {
+			continue
+		}
+		if arg.Category.Valid && (!ff.Category.Valid || ff.Category.String != arg.Category.String) {
+			continue
+		}
+		timeline = append(timeline, p)
+	}
+	postsByDate(timeline)
+
+	// Only the newest copy of a link published by several feeds is listed.
+	seen := map[string]bool{}
+	var posts []database.Post
+	for _, p := range timeline {
+		key := p.Url
+		if key == "" {
+			key = p.ID.String()
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		if arg.CursorPublishedAt.Valid {
+			at := arg.CursorPublishedAt.Time
+			if p.PublishedAt.After(at) || (p.PublishedAt.Equal(at) && p.ID.String() >= arg.CursorID.UUID.String()) {
+				continue
+			}
+		}
+		state := db.states[stateKey{arg.UserID, p.ID}]
+		if arg.UnreadOnly && state.ReadAt.Valid {
+			continue
+		}
+		if arg.StarredOnly && !state.StarredAt.Valid {
+			continue
+		}
+		posts = append(posts, p)
+	}
+
+	if int(arg.Offset) >= len(posts) {
+		return nil, nil
+	}
+	posts = posts[arg.Offset:]
+	if int(arg.Limit) < len(posts) {
+		posts = posts[:arg.Limit]
+	}
+	return posts, nil
+}
+
+// SearchPostsByUser approximates the full text search with a case
+// insensitive match of every query term against the title and description.
+// The rank is the number of term occurrences.
+func (db *DB) SearchPostsByUser(ctx context.Context, arg database.SearchPostsByUserParams) ([]database.SearchPostsByUserRow, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	terms := strings.Fields(strings.ToLower(arg.Query))
+	var rows []database.SearchPostsByUserRow
+	for _, p := range db.posts {
+		if _, ok := db.followOf(arg.UserID, p.FeedID); !ok || len(terms) == 0 {
+			continue
+		}
+		text := p.Title
+		if p.Description.Valid {
+			text = p.Description.String
+		}
+		haystack := strings.ToLower(p.Title + " " + p.Description.String)
+
+		rank := 0
+		for _, term := range terms {
+			n := strings.Count(haystack, term)
+			if n == 0 {
+				rank = 0
+				break
+			}
+			rank += n
+		}
+		if rank == 0 {
+			continue
+		}
+		rows = append(rows, database.SearchPostsByUserRow{
+			ID:          p.ID,
+			Title:       p.Title,
+			Url:         p.Url,
+			PublishedAt: p.PublishedAt,
+			FeedName:    db.feeds[db.feedIndex(p.FeedID)].Name,
+			Rank:        float32(rank),
+			Snippet:     highlight(text, terms),
+		})
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].Rank != rows[j].Rank {
+			return rows[i].Rank > rows[j].Rank
+		}
+		return rows[i].PublishedAt.After(rows[j].PublishedAt)
+	})
+	if int(arg.Limit) < len(rows) {
+		rows = rows[:arg.Limit]
+	}
+	return rows, nil
+}
+
+// highlight wraps the occurrences of the terms between << and >>, like the
+// ts_headline options used by the real query.
+func highlight(text string, terms []string) string {
+	lower := strings.ToLower(text)
+	var b strings.Builder
+	for i := 0; i < len(text); {
+		matched := ""
+		for _, term := range terms {
+			if strings.HasPrefix(lower[i:], term) && len(term) > len(matched) {
+				matched = term
+			}
+		}
+		if matched == "" {
+			b.WriteByte(text[i])
+			i++
+			continue
+		}
+		b.WriteString("<<" + text[i:i+len(matched)] + ">>")
+		i += len(matched)
+	}
+	return b.String()
+}
+
+func (db *DB) UpdatePost(ctx context.Context, arg database.UpdatePostParams) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	i := db.postIndex(arg.ID)
+	if i < 0 {
+		return nil
+	}
+	for j, p := range db.posts {
+		if j != i && p.FeedID == db.posts[i].FeedID && p.Url != "" && p.Url == arg.Url {
+			return uniqueViolation("posts_feed_id_url_idx")
+		}
+	}
+	db.posts[i].Title = arg.Title
+	db.posts[i].Url = arg.Url
+	db.posts[i].Description = arg.Description
+	db.posts[i].UpdatedAt = arg.UpdatedAt
+	return nil
+}
+
+func (db *DB) UpsertPost(ctx context.Context, arg database.UpsertPostParams) (bool, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if db.feedIndex(arg.FeedID) < 0 {
+		return false, foreignKeyViolation("posts_feed_id_fkey")
+	}
+	for i, p := range db.posts {
+		if p.FeedID != arg.FeedID || p.Url == "" || p.Url != arg.Url {
+			continue
+		}
+		if p.Title == arg.Title && p.Description == arg.Description {
+			return false, sql.ErrNoRows
+		}
+		db.posts[i].Title = arg.Title
+		db.posts[i].Description = arg.Description
+		db.posts[i].UpdatedAt = arg.UpdatedAt
+		if !p.Guid.Valid {
+			db.posts[i].Guid = arg.Guid
+		}
+		return false, nil
+	}
+	db.posts = append(db.posts, database.Post{
+		ID:          arg.ID,
+		CreatedAt:   arg.CreatedAt,
+		UpdatedAt:   arg.UpdatedAt,
+		Title:       arg.Title,
+		Url:         arg.Url,
+		Description: arg.Description,
+		PublishedAt: arg.PublishedAt,
+		FeedID:      arg.FeedID,
+		Guid:        arg.Guid,
+	})
+	return true, nil
+}
+
+// Post states
+
+func (db *DB) GetPostStates(ctx context.Context) ([]database.PostState, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	states := make([]database.PostState, 0, len(db.states))
+	for _, state := range db.states {
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+func (db *DB) GetPostStatesAfter(ctx context.Context, arg database.GetPostStatesAfterParams) ([]database.PostState, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	states := make([]database.PostState, 0, len(db.states))
+	for _, state := range db.states {
+		states = append(states, state)
+	}
+	after := arg.AfterUserID.String() + "/" + arg.AfterPostID.String()
+	return pageAfter(states, func(ps database.PostState) string { return ps.UserID.String() + "/" + ps.PostID.String() }, after, arg.Limit), nil
+}
+
+func (db *DB) MarkPostRead(ctx context.Context, arg database.MarkPostReadParams) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if db.userIndex(arg.UserID) < 0 || db.postIndex(arg.PostID) < 0 {
+		return foreignKeyViolation("post_states_post_id_fkey")
+	}
+	key := stateKey{arg.UserID, arg.PostID}
+	state := db.states[key]
+	state.UserID, state.PostID, state.ReadAt = arg.UserID, arg.PostID, arg.ReadAt
+	db.states[key] = state
+	return nil
+}
+
+func (db *DB) MarkPostUnread(ctx context.Context, arg database.MarkPostUnreadParams) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	key := stateKey{arg.UserID, arg.PostID}
+	if state, ok := db.states[key]; ok {
+		state.ReadAt = sql.NullTime{}
+		db.states[key] = state
+	}
+	return nil
+}
+
+func (db *DB) RestorePostState(ctx context.Context, arg database.RestorePostStateParams) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if db.userIndex(arg.UserID) < 0 || db.postIndex(arg.PostID) < 0 {
+		return foreignKeyViolation("post_states_post_id_fkey")
+	}
+	key := stateKey{arg.UserID, arg.PostID}
+	if _, ok := db.states[key]; ok {
+		return uniqueViolation("post_states_pkey")
+	}
+	db.states[key] = database.PostState(arg)
+	return nil
+}
+
+func (db *DB) StarPost(ctx context.Context, arg database.StarPostParams) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if db.userIndex(arg.UserID) < 0 || db.postIndex(arg.PostID) < 0 {
+		return foreignKeyViolation("post_states_post_id_fkey")
+	}
+	key := stateKey{arg.UserID, arg.PostID}
+	state := db.states[key]
+	state.UserID, state.PostID, state.StarredAt = arg.UserID, arg.PostID, arg.StarredAt
+	db.states[key] = state
+	return nil
+}
+
+func (db *DB) UnstarPost(ctx context.Context, arg database.UnstarPostParams) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	key := stateKey{arg.UserID, arg.PostID}
+	if state, ok := db.states[key]; ok {
+		state.StarredAt = sql.NullTime{}
+		db.states[key] = state
+	}
+	return nil
+}
@@ -0,0 +1,387 @@
+// Package migrate applies the goose migrations embedded in the binary. It
+// keeps track of the applied versions in the goose_db_version table, so
+// databases migrated with the goose CLI keep working and vice versa.
+package migrate
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const versionTable = "goose_db_version"
+
+// A single migration file, NNN_name.sql, split in its Up and Down sections.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// State of a migration in the database, AppliedAt is zero when pending.
+type Status struct {
+	Migration Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Dialect holds the SQL that differs between the supported databases, the
+// same version table layout goose uses for each of them.
+type Dialect struct {
+	createVersionTable string
+	versionTableExists string
+}
+
+var (
+	Postgres = Dialect{
+		createVersionTable: `CREATE TABLE IF NOT EXISTS ` + versionTable + ` (
+	id SERIAL PRIMARY KEY,
+	version_id BIGINT NOT NULL,
+	is_applied BOOLEAN NOT NULL,
+	tstamp TIMESTAMP DEFAULT now()
+)`,
+		versionTableExists: `SELECT to_regclass('` + versionTable + `') IS NOT NULL`,
+	}
+	SQLite = Dialect{
+		createVersionTable: `CREATE TABLE IF NOT EXISTS ` + versionTable + ` (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	version_id INTEGER NOT NULL,
+	is_applied INTEGER NOT NULL,
+	tstamp TIMESTAMP DEFAULT (datetime('now'))
+)`,
+		versionTableExists: `SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = '` + versionTable + `')`,
+	}
+)
+
+type Migrator struct {
+	db         *sql.DB
+	dialect    Dialect
+	migrations []Migration
+}
+
+// Reads the migrations in fsys and returns a migrator applying them to db.
+func New(db *sql.DB, fsys fs.FS, dialect Dialect) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{db: db, dialect: dialect, migrations: migrations}, nil
+}
+
+// Parses every .sql file in the root of fsys, sorted by version.
+func Load(fsys fs.FS) ([]Migration, error) {
+	files, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations []Migration
+	seen := map[int64]string{}
+
+	for _, file := range files {
+		name := strings.TrimSuffix(path.Base(file), ".sql")
+
+		prefix, _, ok := strings.Cut(name, "_")
+		if !ok {
+			return nil, fmt.Errorf("migration %v: file name must be NNN_name.sql", file)
+		}
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil || version < 1 {
+			return nil, fmt.Errorf("migration %v: invalid version %q", file, prefix)
+		}
+		if other, ok := seen[version]; ok {
+			return nil, fmt.Errorf("migrations %v and %v share version %v", other, name, version)
+		}
+		seen[version] = name
+
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+
+		up, down, err := parse(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("migration %v: %w", file, err)
+		}
+
+		migrations = append(migrations, Migration{Version: version, Name: name, Up: up, Down: down})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Splits a goose file in its Up and Down sections. Each section is run as a
+// single script, so the StatementBegin and StatementEnd annotations are only
+// skipped.
+func parse(data string) (string, string, error) {
+	var up, down strings.Builder
+	var section *strings.Builder
+
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		annotation, ok := strings.CutPrefix(strings.TrimSpace(line), "-- +goose ")
+		if ok {
+			switch strings.TrimSpace(annotation) {
+			case "Up":
+				section = &up
+			case "Down":
+				section = &down
+			}
+			continue
+		}
+
+		if section == nil {
+			if strings.TrimSpace(line) != "" && !strings.HasPrefix(strings.TrimSpace(line), "--") {
+				return "", "", errors.New("statement before the -- +goose Up annotation")
+			}
+			continue
+		}
+
+		section.WriteString(line)
+		section.WriteString("\n")
+	}
+	if err := scanner.Err(); err != nil {
+		return "", "", err
+	}
+
+	if section == nil {
+		return "", "", errors.New("missing -- +goose Up annotation")
+	}
+
+	return strings.TrimSpace(up.String()), strings.TrimSpace(down.String()), nil
+}
+
+// Version of the last embedded migration, the one the code expects.
+func (m *Migrator) Latest() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+func (m *Migrator) ensureVersionTable(ctx context.Context) error {
+	_, err := m.db.ExecContext(ctx, m.dialect.createVersionTable)
+	if err != nil {
+		return err
+	}
+
+	// goose stores version 0 when it creates the table, do the same.
+	_, err = m.db.ExecContext(ctx, `INSERT INTO `+versionTable+` (version_id, is_applied)
+SELECT 0, true WHERE NOT EXISTS (SELECT 1 FROM `+versionTable+`)`)
+
+	return err
+}
+
+type versionRow struct {
+	version   int64
+	applied   bool
+	appliedAt time.Time
+}
+
+// Reads the applied versions, older goose releases recorded rollbacks as
+// rows with is_applied false instead of deleting them. Nothing is applied
+// while the version table doesn't exist, it is only created by Up.
+func (m *Migrator) applied(ctx context.Context) (map[int64]time.Time, error) {
+	var exists bool
+	err := m.db.QueryRowContext(ctx, m.dialect.versionTableExists).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return map[int64]time.Time{}, nil
+	}
+
+	rows, err := m.db.QueryContext(ctx, `SELECT version_id, is_applied, tstamp FROM `+versionTable+` ORDER BY id DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versions []versionRow
+	for rows.Next() {
+		var row versionRow
+		var tstamp sql.NullTime
+		if err := rows.Scan(&row.version, &row.applied, &tstamp); err != nil {
+			return nil, err
+		}
+		row.appliedAt = tstamp.Time
+		versions = append(versions, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return appliedVersions(versions), nil
+}
+
+// Only the most recent row of each version counts.
+func appliedVersions(rows []versionRow) map[int64]time.Time {
+	applied := map[int64]time.Time{}
+	seen := map[int64]bool{}
+
+	for _, row := range rows {
+		if seen[row.version] || row.version == 0 {
+			continue
+		}
+		seen[row.version] = true
+
+		if row.applied {
+			applied[row.version] = row.appliedAt
+		}
+	}
+
+	return applied
+}
+
+// Highest applied version, 0 for an empty database.
+func (m *Migrator) Version(ctx context.Context) (int64, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var version int64
+	for v := range applied {
+		version = max(version, v)
+	}
+
+	return version, nil
+}
+
+// Every embedded migration and whether it has been applied.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		appliedAt, ok := applied[migration.Version]
+		statuses = append(statuses, Status{Migration: migration, Applied: ok, AppliedAt: appliedAt})
+	}
+
+	return statuses, nil
+}
+
+// Applies every pending migration in order, each one in its own transaction,
+// and returns the ones applied.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	err := m.ensureVersionTable(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+
+		err := m.run(ctx, migration.Up, `INSERT INTO `+versionTable+` (version_id, is_applied) VALUES ($1, true)`, migration.Version)
+		if err != nil {
+			return done, fmt.Errorf("applying %v: %w", migration.Name, err)
+		}
+
+		done = append(done, migration)
+	}
+
+	return done, nil
+}
+
+// Rolls back the last applied migration. It returns false when there is
+// nothing to roll back.
+func (m *Migrator) Down(ctx context.Context) (Migration, bool, error) {
+	version, err := m.Version(ctx)
+	if err != nil {
+		return Migration{}, false, err
+	}
+	if version == 0 {
+		return Migration{}, false, nil
+	}
+
+	for _, migration := range m.migrations {
+		if migration.Version != version {
+			continue
+		}
+
+		err := m.run(ctx, migration.Down, `DELETE FROM `+versionTable+` WHERE version_id = $1`, migration.Version)
+		if err != nil {
+			return migration, false, fmt.Errorf("rolling back %v: %w", migration.Name, err)
+		}
+
+		return migration, true, nil
+	}
+
+	return Migration{}, false, fmt.Errorf("applied version %v has no embedded migration", version)
+}
+
+// Runs the script of a migration and records it in the version table in a
+// single transaction.
+func (m *Migrator) run(ctx context.Context, script, record string, version int64) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if script != "" {
+		_, err = tx.ExecContext(ctx, script)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, record, version)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ErrSchemaMismatch is returned by Check when the database is not at the
+// version of the embedded migrations.
+var ErrSchemaMismatch = errors.New("database schema version mismatch")
+
+// Fails unless the database is exactly at the latest embedded version. It
+// only reads the database, a database without version table is at version 0.
+func (m *Migrator) Check(ctx context.Context) error {
+	version, err := m.Version(ctx)
+	if err != nil {
+		return fmt.Errorf("reading the database schema version: %w", err)
+	}
+
+	latest := m.Latest()
+
+	switch {
+	case version < latest:
+		return fmt.Errorf("%w: the database is at version %v but gator needs version %v, run `gator migrate up`", ErrSchemaMismatch, version, latest)
+	case version > latest:
+		return fmt.Errorf("%w: the database is at version %v, newer than the version %v known to this gator binary, upgrade gator", ErrSchemaMismatch, version, latest)
+	}
+
+	return nil
+}
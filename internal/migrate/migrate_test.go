@@ -0,0 +1,156 @@
+package migrate
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/Alb3G/gator/sql/schema"
+)
+
+func TestLoadEmbeddedMigrations(t *testing.T) {
+	migrations, err := Load(schema.Migrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(migrations) == 0 {
+		t.Fatal("no migrations embedded")
+	}
+
+	for i, migration := range migrations {
+		if migration.Version != int64(i+1) {
+			t.Errorf("migration %v has version %v, want %v", migration.Name, migration.Version, i+1)
+		}
+		if migration.Up == "" || migration.Down == "" {
+			t.Errorf("migration %v is missing its Up or Down section", migration.Name)
+		}
+	}
+
+	if migrations[0].Name != "001_users" || !strings.HasPrefix(migrations[0].Up, "CREATE TABLE users") {
+		t.Errorf("first migration = %+v", migrations[0])
+	}
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   fstest.MapFS
+		want    []Migration
+		wantErr string
+	}{
+		{
+			name: "sorted by version",
+			files: fstest.MapFS{
+				"10_b.sql": {Data: []byte("-- +goose Up\nCREATE TABLE b();\n-- +goose Down\nDROP TABLE b;")},
+				"2_a.sql":  {Data: []byte("-- a comment\n-- +goose Up\nCREATE TABLE a();\n\n-- +goose Down\nDROP TABLE a;\n")},
+			},
+			want: []Migration{
+				{Version: 2, Name: "2_a", Up: "CREATE TABLE a();", Down: "DROP TABLE a;"},
+				{Version: 10, Name: "10_b", Up: "CREATE TABLE b();", Down: "DROP TABLE b;"},
+			},
+		},
+		{
+			name: "statement blocks",
+			files: fstest.MapFS{
+				"1_f.sql": {Data: []byte("-- +goose Up\n-- +goose StatementBegin\nCREATE FUNCTION f() RETURNS int AS $$ SELECT 1; $$ LANGUAGE sql;\n-- +goose StatementEnd\n-- +goose Down\nDROP FUNCTION f;")},
+			},
+			want: []Migration{
+				{Version: 1, Name: "1_f", Up: "CREATE FUNCTION f() RETURNS int AS $$ SELECT 1; $$ LANGUAGE sql;", Down: "DROP FUNCTION f;"},
+			},
+		},
+		{
+			name:    "missing up",
+			files:   fstest.MapFS{"1_a.sql": {Data: []byte("CREATE TABLE a();")}},
+			wantErr: "statement before the -- +goose Up annotation",
+		},
+		{
+			name:    "empty file",
+			files:   fstest.MapFS{"1_a.sql": {Data: []byte("")}},
+			wantErr: "missing -- +goose Up annotation",
+		},
+		{
+			name:    "invalid version",
+			files:   fstest.MapFS{"v1_a.sql": {Data: []byte("-- +goose Up")}},
+			wantErr: `invalid version "v1"`,
+		},
+		{
+			name:    "no name",
+			files:   fstest.MapFS{"001.sql": {Data: []byte("-- +goose Up")}},
+			wantErr: "file name must be NNN_name.sql",
+		},
+		{
+			name: "duplicated version",
+			files: fstest.MapFS{
+				"1_a.sql":   {Data: []byte("-- +goose Up")},
+				"001_b.sql": {Data: []byte("-- +goose Up")},
+			},
+			wantErr: "share version 1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrations, err := Load(tt.files)
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(migrations) != len(tt.want) {
+				t.Fatalf("got %v migrations, want %v", len(migrations), len(tt.want))
+			}
+			for i := range tt.want {
+				if migrations[i] != tt.want[i] {
+					t.Errorf("migration %v = %+v, want %+v", i, migrations[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestAppliedVersions(t *testing.T) {
+	at := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	// Newest first, like the query. Version 3 was applied and then rolled
+	// back by an old goose release that recorded the rollback as a row.
+	rows := []versionRow{
+		{version: 3, applied: false},
+		{version: 3, applied: true, appliedAt: at},
+		{version: 2, applied: true, appliedAt: at},
+		{version: 1, applied: true, appliedAt: at},
+		{version: 0, applied: true},
+	}
+
+	applied := appliedVersions(rows)
+
+	if len(applied) != 2 {
+		t.Fatalf("applied = %v, want versions 1 and 2", applied)
+	}
+	if _, ok := applied[3]; ok {
+		t.Error("rolled back version 3 reported as applied")
+	}
+	if applied[2] != at {
+		t.Errorf("version 2 applied at %v, want %v", applied[2], at)
+	}
+}
+
+func TestLatest(t *testing.T) {
+	m := &Migrator{migrations: []Migration{{Version: 1}, {Version: 2}}}
+
+	if m.Latest() != 2 {
+		t.Errorf("latest = %v, want 2", m.Latest())
+	}
+
+	empty := &Migrator{}
+	if empty.Latest() != 0 {
+		t.Errorf("latest without migrations = %v, want 0", empty.Latest())
+	}
+}
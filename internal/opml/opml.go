@@ -0,0 +1,171 @@
+package opml
+
+import (
+	"encoding/xml"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+type OPML struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    Head     `xml:"head"`
+	Body    Body     `xml:"body"`
+}
+
+type Head struct {
+	Title       string `xml:"title,omitempty"`
+	DateCreated string `xml:"dateCreated,omitempty"`
+}
+
+type Body struct {
+	Outlines []Outline `xml:"outline"`
+}
+
+type Outline struct {
+	Text     string    `xml:"text,attr"`
+	Title    string    `xml:"title,attr,omitempty"`
+	Type     string    `xml:"type,attr,omitempty"`
+	XMLURL   string    `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL  string    `xml:"htmlUrl,attr,omitempty"`
+	Outlines []Outline `xml:"outline"`
+}
+
+// A subscription found in or written to an OPML document. Category is the
+// path of the folders containing it, joined with "/".
+type Subscription struct {
+	Title    string
+	URL      string
+	Category string
+}
+
+func Read(r io.Reader) ([]Subscription, error) {
+	var doc OPML
+	err := xml.NewDecoder(r).Decode(&doc)
+	if err != nil {
+		return nil, err
+	}
+
+	subscriptions := []Subscription{}
+	for _, outline := range doc.Body.Outlines {
+		subscriptions = collect(subscriptions, outline, nil)
+	}
+
+	return subscriptions, nil
+}
+
+// Walks the outline tree, outlines with an xmlUrl are subscriptions and any
+// other outline is a folder.
+func collect(subscriptions []Subscription, outline Outline, folders []string) []Subscription {
+	name := outline.Title
+	if name == "" {
+		name = outline.Text
+	}
+
+	if outline.XMLURL != "" {
+		if name == "" {
+			name = outline.XMLURL
+		}
+
+		return append(subscriptions, Subscription{
+			Title:    name,
+			URL:      outline.XMLURL,
+			Category: strings.Join(folders, "/"),
+		})
+	}
+
+	if name != "" {
+		folders = append(folders[:len(folders):len(folders)], name)
+	}
+
+	for _, child := range outline.Outlines {
+		subscriptions = collect(subscriptions, child, folders)
+	}
+
+	return subscriptions
+}
+
+// Writes the subscriptions as an OPML 2.0 document, every category becomes a
+// folder outline, nested when the category is a path.
+func Write(w io.Writer, title string, subscriptions []Subscription) error {
+	doc := OPML{
+		Version: "2.0",
+		Head: Head{
+			Title:       title,
+			DateCreated: time.Now().UTC().Format(time.RFC1123Z),
+		},
+	}
+
+	root := &folder{}
+	for _, sub := range subscriptions {
+		current := root
+		if sub.Category != "" {
+			for _, name := range strings.Split(sub.Category, "/") {
+				current = current.child(name)
+			}
+		}
+
+		current.feeds = append(current.feeds, Outline{
+			Text:   sub.Title,
+			Title:  sub.Title,
+			Type:   "rss",
+			XMLURL: sub.URL,
+		})
+	}
+	doc.Body.Outlines = root.outlines()
+
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	err = encoder.Encode(doc)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+type folder struct {
+	name     string
+	feeds    []Outline
+	children []*folder
+}
+
+func (f *folder) child(name string) *folder {
+	for _, child := range f.children {
+		if child.name == name {
+			return child
+		}
+	}
+
+	child := &folder{name: name}
+	f.children = append(f.children, child)
+
+	return child
+}
+
+// Feeds without a folder first, then the folders sorted by name.
+func (f *folder) outlines() []Outline {
+	outlines := append([]Outline{}, f.feeds...)
+
+	sort.Slice(f.children, func(i, j int) bool {
+		return f.children[i].name < f.children[j].name
+	})
+
+	for _, child := range f.children {
+		outlines = append(outlines, Outline{
+			Text:     child.name,
+			Title:    child.name,
+			Outlines: child.outlines(),
+		})
+	}
+
+	return outlines
+}
@@ -0,0 +1,252 @@
+// Package output renders the results of the read commands in the format
+// picked with --format: aligned tables for humans, JSON, JSON lines or CSV
+// for scripts, or a Go template for anything else.
+package output
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
+)
+
+type Format string
+
+const (
+	Table    Format = "table"
+	JSON     Format = "json"
+	JSONL    Format = "jsonl"
+	CSV      Format = "csv"
+	Template Format = "template"
+)
+
+var formats = []Format{Table, JSON, JSONL, CSV, Template}
+
+func (f *Format) String() string {
+	return string(*f)
+}
+
+func (f *Format) Set(value string) error {
+	for _, format := range formats {
+		if Format(value) == format {
+			*f = format
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unknown format %q, expected one of table, json, jsonl, csv or template", value)
+}
+
+// Times in tables are shortened, CSV and JSON keep the full RFC3339 time.
+const tableTimeLayout = "2006-01-02 15:04"
+
+// Options picked on the command line with the flags registered by AddFlags.
+type Options struct {
+	Format   Format
+	Template string
+}
+
+// Registers --format, its --json shorthand and --template on fs. Giving a
+// template selects the template format.
+func AddFlags(fs *flag.FlagSet) *Options {
+	opts := &Options{Format: Table}
+
+	fs.Var(formatFlag{opts}, "format", "output `format`: table, json, jsonl, csv or template")
+	fs.BoolFunc("json", "shorthand for --format json", func(string) error {
+		opts.Format = JSON
+		return nil
+	})
+	fs.Func("template", "Go `template` rendered for every item, e.g. '{{.Url}}'", func(text string) error {
+		opts.Format = Template
+		opts.Template = text
+		return nil
+	})
+
+	return opts
+}
+
+// Returns the options set by the flags AddFlags registered on fs.
+func FromFlags(fs *flag.FlagSet) *Options {
+	return fs.Lookup("format").Value.(flag.Getter).Get().(*Options)
+}
+
+// The --format flag, it keeps the options it sets so FromFlags can find
+// them in the flag set.
+type formatFlag struct {
+	opts *Options
+}
+
+func (f formatFlag) String() string {
+	if f.opts == nil {
+		return ""
+	}
+
+	return string(f.opts.Format)
+}
+
+func (f formatFlag) Set(value string) error {
+	return f.opts.Format.Set(value)
+}
+
+func (f formatFlag) Get() any {
+	return f.opts
+}
+
+// Whether the output is meant for humans, commands print hints and notices
+// only in this case so the other formats stay parseable.
+func (o *Options) Human() bool {
+	return o.Format == Table
+}
+
+// A column of the table and CSV outputs. Value returns a string, a time, or
+// a pointer to one of them that is nil when the value is missing.
+type Column[T any] struct {
+	Header string
+	Value  func(T) any
+}
+
+// How the items of a command are rendered. Record returns the value encoded
+// in the JSON outputs and passed to templates.
+type View[T any] struct {
+	Columns []Column[T]
+	Record  func(T) any
+}
+
+// Writes items to w in the format of opts.
+func Render[T any](w io.Writer, opts *Options, items []T, view View[T]) error {
+	switch opts.Format {
+	case Table:
+		return renderTable(w, items, view)
+	case JSON:
+		records := make([]any, 0, len(items))
+		for _, item := range items {
+			records = append(records, view.Record(item))
+		}
+
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(records)
+	case JSONL:
+		enc := json.NewEncoder(w)
+		for _, item := range items {
+			err := enc.Encode(view.Record(item))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case CSV:
+		return renderCSV(w, items, view)
+	case Template:
+		return renderTemplate(w, opts.Template, items, view)
+	default:
+		return fmt.Errorf("unknown format %q", opts.Format)
+	}
+}
+
+func renderTable[T any](w io.Writer, items []T, view View[T]) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	headers := make([]string, len(view.Columns))
+	for i, column := range view.Columns {
+		headers[i] = column.Header
+	}
+	fmt.Fprintln(tw, strings.Join(headers, "\t"))
+
+	for _, item := range items {
+		cells := make([]string, len(view.Columns))
+		for i, column := range view.Columns {
+			cells[i] = formatValue(column.Value(item), true)
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+
+	return tw.Flush()
+}
+
+func renderCSV[T any](w io.Writer, items []T, view View[T]) error {
+	cw := csv.NewWriter(w)
+
+	headers := make([]string, len(view.Columns))
+	for i, column := range view.Columns {
+		headers[i] = strings.ToLower(strings.ReplaceAll(column.Header, " ", "_"))
+	}
+	cw.Write(headers)
+
+	for _, item := range items {
+		cells := make([]string, len(view.Columns))
+		for i, column := range view.Columns {
+			cells[i] = formatValue(column.Value(item), false)
+		}
+		cw.Write(cells)
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func renderTemplate[T any](w io.Writer, text string, items []T, view View[T]) error {
+	if text == "" {
+		return errors.New("the template format needs a --template")
+	}
+
+	tmpl, err := template.New("output").Parse(text)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		err := tmpl.Execute(w, view.Record(item))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+	}
+
+	return nil
+}
+
+// Formats a column value. Tables show local times and a dash for missing
+// values, CSV keeps UTC times and empty cells.
+func formatValue(value any, table bool) string {
+	missing := ""
+	if table {
+		missing = "-"
+	}
+
+	switch v := value.(type) {
+	case time.Time:
+		return formatTime(v, table)
+	case *time.Time:
+		if v == nil {
+			return missing
+		}
+		return formatTime(*v, table)
+	case *string:
+		if v == nil || *v == "" {
+			return missing
+		}
+		return *v
+	case string:
+		if v == "" {
+			return missing
+		}
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func formatTime(t time.Time, table bool) string {
+	if table {
+		return t.Local().Format(tableTimeLayout)
+	}
+
+	return t.UTC().Format(time.RFC3339)
+}
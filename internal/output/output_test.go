@@ -0,0 +1,135 @@
+package output
+
+import (
+	"flag"
+	"strings"
+	"testing"
+	"time"
+)
+
+type item struct {
+	Name    string
+	Note    *string
+	Updated *time.Time
+}
+
+var view = View[item]{
+	Columns: []Column[item]{
+		{Header: "NAME", Value: func(i item) any { return i.Name }},
+		{Header: "NOTE", Value: func(i item) any { return i.Note }},
+		{Header: "LAST UPDATED", Value: func(i item) any { return i.Updated }},
+	},
+	Record: func(i item) any { return i },
+}
+
+func TestRender(t *testing.T) {
+	note := "quoted, with a comma"
+	updated := time.Date(2024, 5, 1, 12, 30, 0, 0, time.FixedZone("CEST", 2*60*60))
+	items := []item{
+		{Name: "first", Note: &note, Updated: &updated},
+		{Name: "second"},
+	}
+
+	tests := []struct {
+		name  string
+		opts  Options
+		items []item
+		want  string
+	}{
+		{
+			name:  "csv",
+			opts:  Options{Format: CSV},
+			items: items,
+			want:  "name,note,last_updated\nfirst,\"quoted, with a comma\",2024-05-01T10:30:00Z\nsecond,,\n",
+		},
+		{
+			name:  "json",
+			opts:  Options{Format: JSON},
+			items: items[1:],
+			want:  "[\n  {\n    \"Name\": \"second\",\n    \"Note\": null,\n    \"Updated\": null\n  }\n]\n",
+		},
+		{
+			name: "empty json",
+			opts: Options{Format: JSON},
+			want: "[]\n",
+		},
+		{
+			name:  "jsonl",
+			opts:  Options{Format: JSONL},
+			items: items,
+			want:  "{\"Name\":\"first\",\"Note\":\"quoted, with a comma\",\"Updated\":\"2024-05-01T12:30:00+02:00\"}\n{\"Name\":\"second\",\"Note\":null,\"Updated\":null}\n",
+		},
+		{
+			name:  "template",
+			opts:  Options{Format: Template, Template: "{{.Name}}{{with .Note}}: {{.}}{{end}}"},
+			items: items,
+			want:  "first: quoted, with a comma\nsecond\n",
+		},
+		{
+			name:  "table",
+			opts:  Options{Format: Table},
+			items: items[1:],
+			want:  "NAME    NOTE  LAST UPDATED\nsecond  -     -\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			err := Render(&b, &tt.opts, tt.items, view)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if b.String() != tt.want {
+				t.Errorf("output = %q, want %q", b.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderTemplateErrors(t *testing.T) {
+	var b strings.Builder
+
+	err := Render(&b, &Options{Format: Template}, []item{{Name: "a"}}, view)
+	if err == nil || !strings.Contains(err.Error(), "needs a --template") {
+		t.Errorf("error without template = %v", err)
+	}
+
+	err = Render(&b, &Options{Format: Template, Template: "{{.Missing}}"}, []item{{Name: "a"}}, view)
+	if err == nil {
+		t.Error("no error for a field the item doesn't have")
+	}
+}
+
+func TestAddFlags(t *testing.T) {
+	tests := []struct {
+		args    []string
+		want    Options
+		wantErr bool
+	}{
+		{args: nil, want: Options{Format: Table}},
+		{args: []string{"--format", "csv"}, want: Options{Format: CSV}},
+		{args: []string{"--json"}, want: Options{Format: JSON}},
+		{args: []string{"--template", "{{.Name}}"}, want: Options{Format: Template, Template: "{{.Name}}"}},
+		{args: []string{"--format", "xml"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(&strings.Builder{})
+		opts := AddFlags(fs)
+
+		err := fs.Parse(tt.args)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parsing %v: error = %v", tt.args, err)
+			continue
+		}
+		if !tt.wantErr && *opts != tt.want {
+			t.Errorf("parsing %v = %+v, want %+v", tt.args, *opts, tt.want)
+		}
+		if FromFlags(fs) != opts {
+			t.Errorf("FromFlags doesn't return the options of the flags")
+		}
+	}
+}
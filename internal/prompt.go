@@ -0,0 +1,104 @@
+package internal
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// Read what the user types, the tests replace them.
+var (
+	readPassword = promptPassword
+	confirm      = promptConfirm
+)
+
+var stdin = bufio.NewReader(os.Stdin)
+
+// Prompts for a password on the terminal without echoing it. When stdin isn't
+// a terminal the password is its next line instead, so scripts can pipe it.
+//
+// Cancelling ctx, as the first Ctrl-C does, abandons the prompt. The terminal
+// state saved beforehand is restored whichever way the prompt ends, so echo
+// isn't left off.
+func promptPassword(ctx context.Context, prompt string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		line, err := stdin.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			return "", fmt.Errorf("reading the password: %w", err)
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	state, err := term.GetState(fd)
+	if err != nil {
+		return "", fmt.Errorf("reading the password: %w", err)
+	}
+	defer term.Restore(fd, state)
+
+	type result struct {
+		password []byte
+		err      error
+	}
+	read := make(chan result, 1)
+
+	fmt.Fprint(os.Stderr, prompt)
+	go func() {
+		password, err := term.ReadPassword(fd)
+		read <- result{password, err}
+	}()
+
+	select {
+	case r := <-read:
+		fmt.Fprintln(os.Stderr)
+		if r.err != nil {
+			return "", fmt.Errorf("reading the password: %w", r.err)
+		}
+		return string(r.password), nil
+	case <-ctx.Done():
+		fmt.Fprintln(os.Stderr)
+		return "", ctx.Err()
+	}
+}
+
+// Reads a new password twice to catch typos.
+func readNewPassword(ctx context.Context, prompt string) (string, error) {
+	password, err := readPassword(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+
+	confirmation, err := readPassword(ctx, "Repeat the password: ")
+	if err != nil {
+		return "", err
+	}
+
+	if password != confirmation {
+		return "", errors.New("the passwords don't match")
+	}
+
+	return password, nil
+}
+
+// Asks a yes or no question on the terminal, anything but y or yes is a no.
+// Without a terminal nobody can answer, so callers need a flag to skip it.
+func promptConfirm(question string) (bool, error) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return false, errors.New("no terminal to confirm on, pass --yes to skip the confirmation")
+	}
+
+	fmt.Fprintf(os.Stderr, "%v [y/N] ", question)
+	answer, err := stdin.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
@@ -0,0 +1,538 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	conf "github.com/Alb3G/gator/internal/config"
+	"github.com/Alb3G/gator/internal/output"
+	rss "github.com/Alb3G/gator/internal/rss"
+)
+
+// Positional argument of a command. Optional arguments go after the
+// required ones and a variadic argument takes all the remaining ones.
+type Arg struct {
+	Name        string
+	Description string
+	Optional    bool
+	Variadic    bool
+	// Fixed values offered by the shell completion.
+	Values []string
+	// Looks up the values offered by the shell completion in the database,
+	// through the hidden __complete command.
+	Complete func(ctx context.Context, s *conf.State) ([]string, error)
+}
+
+// Spec declares a command: what help shows about it, the arguments and
+// flags it accepts and the handler running it.
+type Spec struct {
+	Name        string
+	Description string
+	Args        []Arg
+	// Defines the flags of the command on its flag set, they are read in
+	// the handler with the typed accessors of Command.
+	Flags   func(fs *flag.FlagSet)
+	Handler func(context.Context, *conf.State, Command) error
+	// The command works on a database that isn't migrated yet.
+	SkipSchemaCheck bool
+	// The command runs without configuration nor database, its handler
+	// gets a nil state.
+	Offline bool
+	// Left out of help and of the shell completion.
+	Hidden bool
+}
+
+// A command line to run. Args[0] is the command name, followed by the
+// positional arguments once the flags have been parsed into Flags.
+type Command struct {
+	Name  string
+	Args  []string
+	Flags *flag.FlagSet
+}
+
+func (c Command) String(name string) string {
+	return c.flag(name).(string)
+}
+
+func (c Command) Bool(name string) bool {
+	return c.flag(name).(bool)
+}
+
+func (c Command) Int(name string) int {
+	return c.flag(name).(int)
+}
+
+func (c Command) flag(name string) any {
+	return c.Flags.Lookup(name).Value.(flag.Getter).Get()
+}
+
+type Commands struct {
+	AvailableCommands map[string]Spec
+}
+
+// Returns the registry of every gator command.
+func NewCommands() *Commands {
+	c := &Commands{AvailableCommands: make(map[string]Spec)}
+
+	c.Register(Spec{
+		Name:        "help",
+		Description: "Show the commands, or the usage of one of them",
+		Args:        []Arg{{Name: "command", Description: "command to describe", Optional: true}},
+		Handler:     c.Help,
+		Offline:     true,
+	})
+	c.Register(Spec{
+		Name:        "login",
+		Description: "Log in as a user, prompting for its password",
+		Args:        []Arg{{Name: "username", Description: "name of a registered user", Complete: completeUserNames}},
+		Handler:     LoginHandler,
+	})
+	c.Register(Spec{
+		Name:        "register",
+		Description: "Create a user with a password and log in as it",
+		Args:        []Arg{{Name: "username", Description: "name of the new user"}},
+		Handler:     RegisterHandler,
+	})
+	c.Register(Spec{
+		Name:        "passwd",
+		Description: "Change the password of the current user",
+		Handler:     MiddlewareLoggedIn(Passwd),
+	})
+	c.Register(Spec{
+		Name:        "setpassword",
+		Description: "Set the password of a user, as an admin or with a setup token",
+		Args:        []Arg{{Name: "username", Description: "name of the user"}},
+		Flags: func(fs *flag.FlagSet) {
+			fs.String("token", "", "setup token printed by `gator migrate up`, instead of being logged in as an admin")
+		},
+		Handler: SetPassword,
+	})
+	c.Register(Spec{
+		Name:        "reset",
+		Description: "Delete every user, feed and post, or only some of them",
+		Flags: func(fs *flag.FlagSet) {
+			fs.Bool("yes", false, "don't ask for confirmation")
+			fs.Bool("posts", false, "only delete the posts, with their read and starred states")
+			fs.Bool("feeds", false, "only delete the feeds, with their follows and posts")
+			fs.String("user", "", "only delete the user with this `name`, with their feeds, follows and post states")
+		},
+		Handler: MiddlewareLoggedIn(ResetHandler),
+	})
+	c.Register(Spec{
+		Name:        "backup",
+		Description: "Write every user, feed and post to an archive",
+		Args:        []Arg{{Name: "file", Description: "gzipped archive to write"}},
+		Handler:     MiddlewareLoggedIn(BackupHandler),
+	})
+	c.Register(Spec{
+		Name:        "restore",
+		Description: "Replace every user, feed and post with an archive written by backup",
+		Args:        []Arg{{Name: "file", Description: "archive to read"}},
+		Flags: func(fs *flag.FlagSet) {
+			fs.Bool("yes", false, "don't ask for confirmation")
+		},
+		Handler: MiddlewareLoggedIn(RestoreHandler),
+	})
+	c.Register(Spec{
+		Name:        "users",
+		Description: "List the registered users",
+		Flags:       outputFlags,
+		Handler:     Users,
+	})
+	c.Register(Spec{
+		Name:        "agg",
+		Description: "Fetch the feeds periodically, until interrupted",
+		Args: []Arg{
+			{Name: "interval", Description: "time between fetches, like 30s or 5m"},
+			{Name: "concurrency", Description: "number of feeds fetched at the same time (default 5)", Optional: true},
+			{Name: "batch_size", Description: "number of feeds fetched every interval (default concurrency)", Optional: true},
+		},
+		Handler: Agg,
+	})
+	c.Register(Spec{
+		Name:        "feedhealth",
+		Description: "List the feeds failing to fetch",
+		Handler:     FeedHealth,
+	})
+	c.Register(Spec{
+		Name:        "serve",
+		Description: "Serve the JSON REST API",
+		Args:        []Arg{{Name: "addr", Description: "address to listen on (default localhost:8080)", Optional: true}},
+		Handler:     Serve,
+	})
+	c.Register(Spec{
+		Name:        "migrate",
+		Description: "Apply, roll back or list the database migrations",
+		Args:        []Arg{{Name: "subcommand", Description: "up, down or status", Values: []string{"up", "down", "status"}}},
+		Handler:     Migrate,
+
+		SkipSchemaCheck: true,
+	})
+	c.Register(Spec{
+		Name:        "addfeed",
+		Description: "Add a feed and follow it",
+		Args: []Arg{
+			{Name: "name", Description: "name of the feed"},
+			{Name: "url", Description: "url of the RSS, Atom or JSON feed"},
+		},
+		Handler: MiddlewareLoggedIn(AddFeed),
+	})
+	c.Register(Spec{
+		Name:        "feeds",
+		Description: "List every feed with its owner",
+		Flags:       outputFlags,
+		Handler:     MiddlewareLoggedIn(FeedsHandler),
+	})
+	c.Register(Spec{
+		Name:        "follow",
+		Description: "Follow an existing feed",
+		Args:        []Arg{{Name: "url", Description: "url of the feed", Complete: completeUnfollowedFeeds}},
+		Handler:     MiddlewareLoggedIn(Follow),
+	})
+	c.Register(Spec{
+		Name:        "following",
+		Description: "List the feeds you follow",
+		Flags:       outputFlags,
+		Handler:     MiddlewareLoggedIn(Following),
+	})
+	c.Register(Spec{
+		Name:        "unfollow",
+		Description: "Unfollow a feed",
+		Args:        []Arg{{Name: "url", Description: "url of the feed", Complete: completeFollowedFeeds}},
+		Handler:     MiddlewareLoggedIn(Unfollow),
+	})
+	c.Register(Spec{
+		Name:        "import",
+		Description: "Follow the feeds of an OPML file",
+		Args:        []Arg{{Name: "file", Description: "OPML file to import"}},
+		Handler:     MiddlewareLoggedIn(Import),
+	})
+	c.Register(Spec{
+		Name:        "export",
+		Description: "Write the feeds you follow as OPML",
+		Args:        []Arg{{Name: "file", Description: "file to write (default: standard output)", Optional: true}},
+		Handler:     MiddlewareLoggedIn(Export),
+	})
+	c.Register(Spec{
+		Name:        "browse",
+		Description: "Show the latest posts of the feeds you follow",
+		Args:        []Arg{{Name: "limit", Description: "number of posts, 1 to 100 (default 2)", Optional: true}},
+		Flags: func(fs *flag.FlagSet) {
+			fs.String("feed", "", "only show posts of the followed feed with this url or name")
+			fs.String("since", "", "only show posts published at or after this date (YYYY-MM-DD or RFC3339)")
+			fs.String("until", "", "only show posts published before this date (YYYY-MM-DD or RFC3339)")
+			fs.Int("offset", 0, "number of posts to skip")
+			fs.String("cursor", "", "show the posts after the cursor printed by a previous page")
+			fs.Bool("unread", false, "only show posts not marked as read")
+			fs.Bool("starred", false, "only show starred posts")
+			fs.String("category", "", "only show posts of the feeds followed in this category")
+			outputFlags(fs)
+		},
+		Handler: MiddlewareLoggedIn(Browse),
+	})
+	c.Register(Spec{
+		Name:        "publish",
+		Description: "Write the posts of the feeds you follow as a single feed",
+		Args:        []Arg{{Name: "file", Description: "file to write"}},
+		Flags: func(fs *flag.FlagSet) {
+			fs.String("format", rss.FormatRSS, "output format, rss or atom")
+			fs.String("category", "", "only publish the feeds followed in this category")
+			fs.Int("limit", 50, "maximum number of posts")
+		},
+		Handler: MiddlewareLoggedIn(Publish),
+	})
+	c.Register(Spec{
+		Name:        "feedtoken",
+		Description: "Create the token feed readers use to fetch your feed from serve",
+		Handler:     MiddlewareLoggedIn(FeedToken),
+	})
+	c.Register(Spec{
+		Name:        "search",
+		Description: "Full-text search over the posts of the feeds you follow",
+		Args:        []Arg{{Name: "query", Description: `words, "quoted phrases", or and -excluded words`, Variadic: true}},
+		Flags: func(fs *flag.FlagSet) {
+			fs.Int("limit", 10, "maximum number of results")
+		},
+		Handler: MiddlewareLoggedIn(Search),
+	})
+	c.Register(Spec{
+		Name:        "read",
+		Description: "Mark a post as read",
+		Args:        []Arg{{Name: "post_id", Description: "id of the post, shown by browse"}},
+		Handler:     MiddlewareLoggedIn(ReadPost),
+	})
+	c.Register(Spec{
+		Name:        "unread",
+		Description: "Mark a post as unread",
+		Args:        []Arg{{Name: "post_id", Description: "id of the post, shown by browse"}},
+		Handler:     MiddlewareLoggedIn(UnreadPost),
+	})
+	c.Register(Spec{
+		Name:        "star",
+		Description: "Star a post",
+		Args:        []Arg{{Name: "post_id", Description: "id of the post, shown by browse"}},
+		Handler:     MiddlewareLoggedIn(StarPost),
+	})
+	c.Register(Spec{
+		Name:        "unstar",
+		Description: "Remove the star of a post",
+		Args:        []Arg{{Name: "post_id", Description: "id of the post, shown by browse"}},
+		Handler:     MiddlewareLoggedIn(UnstarPost),
+	})
+	c.Register(Spec{
+		Name:        "completion",
+		Description: "Print the shell completion script",
+		Args:        []Arg{{Name: "shell", Description: "bash, zsh or fish", Values: []string{"bash", "zsh", "fish"}}},
+		Handler:     c.Completion,
+		Offline:     true,
+	})
+	c.Register(Spec{
+		Name:        "__complete",
+		Description: "Print the completions of the last word of a command line",
+		Args:        []Arg{{Name: "words", Description: "command name and arguments, the last one being completed", Variadic: true}},
+		Handler:     c.Complete,
+		Hidden:      true,
+
+		SkipSchemaCheck: true,
+	})
+
+	// help completes the names of the commands, known only now.
+	c.AvailableCommands["help"].Args[0].Values = c.names()
+
+	return c
+}
+
+func outputFlags(fs *flag.FlagSet) {
+	output.AddFlags(fs)
+}
+
+// This method registers a new command.
+func (c *Commands) Register(spec Spec) {
+	c.AvailableCommands[spec.Name] = spec
+}
+
+func (c *Commands) Lookup(name string) (Spec, bool) {
+	spec, ok := c.AvailableCommands[name]
+	return spec, ok
+}
+
+// This method parses the flags and arguments of a command and runs it with
+// the provided state. Wrong flags or arguments are reported with the usage
+// of the command, -h or --help prints its help instead of running it.
+//
+// ctx is cancelled when gator is asked to stop.
+func (c *Commands) Run(ctx context.Context, state *conf.State, cmd Command) error {
+	spec, ok := c.AvailableCommands[cmd.Name]
+	if !ok {
+		return fmt.Errorf("unknown command %q, run `gator help` to list the commands", cmd.Name)
+	}
+
+	fs := spec.flagSet()
+
+	var args []string
+	if len(cmd.Args) > 0 {
+		args = cmd.Args[1:]
+	}
+
+	err := fs.Parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		spec.printHelp(os.Stdout)
+		return nil
+	}
+	if err == nil {
+		err = checkFlags(fs, args)
+	}
+	if err == nil {
+		err = spec.checkArgs(fs.Args())
+	}
+	if err != nil {
+		return fmt.Errorf("%w\nUsage: %v\nRun `gator help %v` for details", err, spec.Usage(), spec.Name)
+	}
+
+	cmd.Args = append([]string{cmd.Name}, fs.Args()...)
+	cmd.Flags = fs
+
+	return spec.Handler(ctx, state, cmd)
+}
+
+// Handler of the help command.
+func (c *Commands) Help(ctx context.Context, s *conf.State, cmd Command) error {
+	if len(cmd.Args) > 1 {
+		spec, ok := c.AvailableCommands[cmd.Args[1]]
+		if !ok {
+			return fmt.Errorf("unknown command %q, run `gator help` to list the commands", cmd.Args[1])
+		}
+
+		spec.printHelp(os.Stdout)
+		return nil
+	}
+
+	c.PrintUsage(os.Stdout)
+
+	return nil
+}
+
+// Names of the commands that aren't hidden, sorted.
+func (c *Commands) names() []string {
+	names := make([]string, 0, len(c.AvailableCommands))
+	for name, spec := range c.AvailableCommands {
+		if !spec.Hidden {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Writes the list of commands.
+func (c *Commands) PrintUsage(w io.Writer) {
+	names := c.names()
+
+	fmt.Fprintln(w, "Gator is an RSS, Atom and JSON feed aggregator.")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Usage: gator <command> [flags] [args]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, name := range names {
+		fmt.Fprintf(tw, "  %v\t%v\n", name, c.AvailableCommands[name].Description)
+	}
+	tw.Flush()
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run `gator help <command>` for the arguments and flags of a command.")
+}
+
+// Checks what flag doesn't. Every int flag of gator is a count or a limit
+// stored in an int32, and flag stops at the first positional argument, so a
+// flag given after it would silently become an argument. Arguments after a
+// -- terminator are left alone, a search can exclude a word spelled like a
+// flag that way.
+func checkFlags(fs *flag.FlagSet, args []string) error {
+	var err error
+	fs.Visit(func(f *flag.Flag) {
+		getter, ok := f.Value.(flag.Getter)
+		if !ok {
+			return
+		}
+		n, ok := getter.Get().(int)
+		if ok && err == nil && (n < 0 || n > math.MaxInt32) {
+			err = fmt.Errorf("invalid value %v for flag --%v: must be between 0 and %v", n, f.Name, math.MaxInt32)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	rest := fs.Args()
+	if n := len(args) - len(rest); n > 0 && args[n-1] == "--" {
+		return nil
+	}
+	for _, arg := range rest {
+		name, ok := strings.CutPrefix(arg, "-")
+		if !ok {
+			continue
+		}
+		name, _, _ = strings.Cut(strings.TrimPrefix(name, "-"), "=")
+		if fs.Lookup(name) != nil {
+			return fmt.Errorf("flag %v given after the arguments, flags go first", arg)
+		}
+	}
+
+	return nil
+}
+
+func (spec Spec) flagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(spec.Name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	if spec.Flags != nil {
+		spec.Flags(fs)
+	}
+
+	return fs
+}
+
+// The usage line of the command, like `gator browse [flags] [limit]`.
+func (spec Spec) Usage() string {
+	parts := []string{"gator", spec.Name}
+
+	hasFlags := false
+	spec.flagSet().VisitAll(func(*flag.Flag) { hasFlags = true })
+	if hasFlags {
+		parts = append(parts, "[flags]")
+	}
+
+	for _, arg := range spec.Args {
+		switch {
+		case arg.Variadic:
+			parts = append(parts, "<"+arg.Name+">...")
+		case arg.Optional:
+			parts = append(parts, "["+arg.Name+"]")
+		default:
+			parts = append(parts, "<"+arg.Name+">")
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// Checks the number of positional arguments.
+func (spec Spec) checkArgs(args []string) error {
+	for i, arg := range spec.Args {
+		if i >= len(args) && !arg.Optional {
+			return fmt.Errorf("missing %v arg", arg.Name)
+		}
+		if arg.Variadic {
+			return nil
+		}
+	}
+
+	if len(args) > len(spec.Args) {
+		return fmt.Errorf("unexpected arg %q", args[len(spec.Args)])
+	}
+
+	return nil
+}
+
+func (spec Spec) printHelp(w io.Writer) {
+	fmt.Fprintf(w, "Usage: %v\n\n%v\n", spec.Usage(), spec.Description)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	if len(spec.Args) > 0 {
+		fmt.Fprintln(tw, "\nArguments:")
+		for _, arg := range spec.Args {
+			fmt.Fprintf(tw, "  %v\t%v\n", arg.Name, arg.Description)
+		}
+	}
+
+	first := true
+	spec.flagSet().VisitAll(func(f *flag.Flag) {
+		if first {
+			fmt.Fprintln(tw, "\nFlags:")
+			first = false
+		}
+
+		name, usage := flag.UnquoteUsage(f)
+		if name != "" {
+			name = " " + name
+		}
+		if f.DefValue != "" && f.DefValue != "0" && f.DefValue != "false" {
+			usage += fmt.Sprintf(" (default %v)", f.DefValue)
+		}
+
+		fmt.Fprintf(tw, "  --%v%v\t%v\n", f.Name, name, usage)
+	})
+
+	tw.Flush()
+}
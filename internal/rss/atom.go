@@ -0,0 +1,109 @@
+package rss
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+type AtomFeed struct {
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle"`
+	Links    []AtomLink  `xml:"link"`
+	Entries  []AtomEntry `xml:"entry"`
+}
+
+type AtomEntry struct {
+	ID        string     `xml:"id"`
+	Title     string     `xml:"title"`
+	Links     []AtomLink `xml:"link"`
+	Updated   string     `xml:"updated"`
+	Published string     `xml:"published"`
+	Summary   AtomText   `xml:"summary"`
+	Content   AtomText   `xml:"content"`
+	Author    struct {
+		Name string `xml:"name"`
+	} `xml:"author"`
+}
+
+// Atom text construct. Text and html content is character data, xhtml content
+// is markup wrapped in a div.
+type AtomText struct {
+	Type  string `xml:"type,attr"`
+	Text  string `xml:",chardata"`
+	Inner string `xml:",innerxml"`
+}
+
+// Returns the text, or the html of the xhtml markup without its wrapping div.
+func (t AtomText) String() string {
+	if t.Type != "xhtml" {
+		return t.Text
+	}
+
+	inner := strings.TrimSpace(t.Inner)
+	start := strings.Index(inner, ">")
+	end := strings.LastIndex(inner, "</")
+	if strings.HasPrefix(inner, "<") && strings.HasSuffix(inner, "div>") && start < end {
+		inner = inner[start+1 : end]
+	}
+
+	return strings.TrimSpace(inner)
+}
+
+type AtomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+}
+
+func parseAtom(data []byte) (*Feed, error) {
+	var atomFeed AtomFeed
+	err := xml.Unmarshal(data, &atomFeed)
+	if err != nil {
+		return nil, err
+	}
+
+	feed := &Feed{
+		Title:       atomFeed.Title,
+		Link:        alternateLink(atomFeed.Links),
+		Description: atomFeed.Subtitle,
+	}
+
+	for _, entry := range atomFeed.Entries {
+		description := entry.Summary.String()
+		if strings.TrimSpace(description) == "" {
+			description = entry.Content.String()
+		}
+
+		// published is optional in Atom, updated is always present.
+		pubDate := entry.Published
+		if pubDate == "" {
+			pubDate = entry.Updated
+		}
+
+		feed.Items = append(feed.Items, Item{
+			GUID:        entry.ID,
+			Title:       entry.Title,
+			Link:        alternateLink(entry.Links),
+			Description: description,
+			PubDate:     pubDate,
+			Author:      entry.Author.Name,
+		})
+	}
+
+	return feed, nil
+}
+
+// Atom links without a rel attribute are alternate links by spec, if there is
+// no alternate link at all we fall back to the first one found.
+func alternateLink(links []AtomLink) string {
+	for _, link := range links {
+		if link.Rel == "" || link.Rel == "alternate" {
+			return link.Href
+		}
+	}
+
+	if len(links) > 0 {
+		return links[0].Href
+	}
+
+	return ""
+}
@@ -0,0 +1,103 @@
+package rss
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+// Feed is the format independent representation of a fetched feed.
+// Every supported format (RSS 2.0, Atom 1.0, JSON Feed) is normalized into it so
+// callers don't need to care about the source document.
+type Feed struct {
+	// Only used when writing Atom documents.
+	ID    string
+	Title string
+	// Left out of the written document when empty, a published file has no
+	// address of its own.
+	Link        string
+	Description string
+	Items       []Item
+	// Set when the server answered 304 Not Modified, Items is empty then.
+	NotModified bool
+	Cache       CacheValidators
+}
+
+type Item struct {
+	GUID        string
+	Title       string
+	Link        string
+	Description string
+	PubDate     string
+	Author      string
+	// Only used when writing feeds, see Write.
+	PublishedAt time.Time
+	Source      *Source
+}
+
+// The feed an item was aggregated from.
+type Source struct {
+	Title string
+	URL   string
+}
+
+var ErrUnknownFormat = errors.New("unknown feed format")
+
+// Parse detects the format of the document and decodes it into a normalized
+// Feed. The content type is only a hint, when it is empty or too generic the
+// body itself is sniffed.
+func Parse(data []byte, contentType string) (*Feed, error) {
+	if isJSON(data, contentType) {
+		return parseJSON(data)
+	}
+
+	root, err := rootElement(data)
+	if err != nil {
+		return nil, err
+	}
+
+	switch root {
+	case "rss":
+		return parseRSS(data)
+	case "feed":
+		return parseAtom(data)
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+// Returns the local name of the first start element of an XML document.
+func rootElement(data []byte) (string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return "", ErrUnknownFormat
+		}
+		if err != nil {
+			return "", err
+		}
+
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name.Local, nil
+		}
+	}
+}
+
+// Servers are often misconfigured and serve JSON Feeds as text/plain, so we
+// also look for a JSON object at the start of the body.
+func isJSON(data []byte, contentType string) bool {
+	contentType = strings.ToLower(contentType)
+	if strings.Contains(contentType, "json") {
+		return true
+	}
+	if strings.Contains(contentType, "xml") {
+		return false
+	}
+
+	trimmed := bytes.TrimLeft(data, " \t\r\n\ufeff")
+	return len(trimmed) > 0 && trimmed[0] == '{'
+}
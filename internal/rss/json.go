@@ -0,0 +1,116 @@
+package rss
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+type JSONFeed struct {
+	Version     string     `json:"version"`
+	Title       string     `json:"title"`
+	HomePageURL string     `json:"home_page_url"`
+	Description string     `json:"description"`
+	Items       []JSONItem `json:"items"`
+}
+
+type JSONItem struct {
+	// A string by the spec, but numbers are published too, see jsonID.
+	ID            json.RawMessage `json:"id"`
+	URL           string          `json:"url"`
+	ExternalURL   string          `json:"external_url"`
+	Title         string          `json:"title"`
+	ContentHTML   string          `json:"content_html"`
+	ContentText   string          `json:"content_text"`
+	Summary       string          `json:"summary"`
+	DatePublished string          `json:"date_published"`
+	DateModified  string          `json:"date_modified"`
+	Authors       []JSONAuthor    `json:"authors"`
+	// Deprecated in JSON Feed 1.1 but still published by plenty of sites.
+	Author *JSONAuthor `json:"author"`
+}
+
+type JSONAuthor struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+func parseJSON(data []byte) (*Feed, error) {
+	var jsonFeed JSONFeed
+	err := json.Unmarshal(data, &jsonFeed)
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.HasPrefix(jsonFeed.Version, "https://jsonfeed.org/version/") {
+		return nil, ErrUnknownFormat
+	}
+
+	feed := &Feed{
+		Title:       jsonFeed.Title,
+		Link:        jsonFeed.HomePageURL,
+		Description: jsonFeed.Description,
+	}
+
+	for _, item := range jsonFeed.Items {
+		link := item.URL
+		if link == "" {
+			link = item.ExternalURL
+		}
+
+		description := item.ContentHTML
+		if description == "" {
+			description = item.ContentText
+		}
+		if description == "" {
+			description = item.Summary
+		}
+
+		pubDate := item.DatePublished
+		if pubDate == "" {
+			pubDate = item.DateModified
+		}
+
+		feed.Items = append(feed.Items, Item{
+			GUID:        jsonID(item.ID),
+			Title:       item.Title,
+			Link:        link,
+			Description: description,
+			PubDate:     pubDate,
+			Author:      jsonAuthors(item),
+		})
+	}
+
+	return feed, nil
+}
+
+// JSON Feed 1.1 asks readers to coerce a number id to a string, an id of any
+// other type is dropped so the item is recognized by its url instead.
+func jsonID(raw json.RawMessage) string {
+	var id string
+	if json.Unmarshal(raw, &id) == nil {
+		return id
+	}
+
+	var number json.Number
+	if json.Unmarshal(raw, &number) == nil {
+		return number.String()
+	}
+
+	return ""
+}
+
+func jsonAuthors(item JSONItem) string {
+	authors := item.Authors
+	if len(authors) == 0 && item.Author != nil {
+		authors = []JSONAuthor{*item.Author}
+	}
+
+	names := []string{}
+	for _, author := range authors {
+		if author.Name != "" {
+			names = append(names, author.Name)
+		}
+	}
+
+	return strings.Join(names, ", ")
+}
@@ -3,6 +3,7 @@ package rss
 import (
 	"context"
 	"encoding/xml"
+	"fmt"
 	"io"
 	"net/http"
 )
@@ -21,34 +22,101 @@ type RSSItem struct {
 	Link        string `xml:"link"`
 	Description string `xml:"description"`
 	PubDate     string `xml:"pubDate"`
+	GUID        string `xml:"guid"`
+	Author      string `xml:"author"`
 }
 
-func FetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
+// Validators from a previous fetch, sent back to the server so it can answer
+// with 304 Not Modified when the feed didn't change.
+type CacheValidators struct {
+	ETag         string
+	LastModified string
+}
+
+func FetchFeed(ctx context.Context, feedURL string, validators CacheValidators) (*Feed, error) {
 	req, err := http.NewRequestWithContext(ctx, "GET", feedURL, nil)
 	if err != nil {
-		return &RSSFeed{}, err
+		return &Feed{}, err
 	}
 	// User_Agent to identify our app
 	req.Header.Set("User-Agent", "gator")
+	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/feed+json, application/xml;q=0.9, */*;q=0.8")
+
+	if validators.ETag != "" {
+		req.Header.Set("If-None-Match", validators.ETag)
+	}
+	if validators.LastModified != "" {
+		req.Header.Set("If-Modified-Since", validators.LastModified)
+	}
 
 	client := &http.Client{}
 
 	res, err := client.Do(req)
 	if err != nil {
-		return &RSSFeed{}, err
+		return &Feed{}, err
 	}
 	defer res.Body.Close()
 
+	// Some servers only send the validators on the 200 response, keep the
+	// previous ones in that case.
+	cache := CacheValidators{
+		ETag:         headerOr(res.Header, "ETag", validators.ETag),
+		LastModified: headerOr(res.Header, "Last-Modified", validators.LastModified),
+	}
+
+	if res.StatusCode == http.StatusNotModified {
+		return &Feed{NotModified: true, Cache: cache}, nil
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return &Feed{}, fmt.Errorf("unexpected status code %v fetching %v", res.StatusCode, feedURL)
+	}
+
 	data, err := io.ReadAll(res.Body)
 	if err != nil {
-		return &RSSFeed{}, err
+		return &Feed{}, err
+	}
+
+	feed, err := Parse(data, res.Header.Get("Content-Type"))
+	if err != nil {
+		return &Feed{}, err
+	}
+	feed.Cache = cache
+
+	return feed, nil
+}
+
+func headerOr(header http.Header, key, fallback string) string {
+	if value := header.Get(key); value != "" {
+		return value
 	}
 
-	var rssFeed *RSSFeed
-	err = xml.Unmarshal(data, &rssFeed)
+	return fallback
+}
+
+func parseRSS(data []byte) (*Feed, error) {
+	var rssFeed RSSFeed
+	err := xml.Unmarshal(data, &rssFeed)
 	if err != nil {
-		return &RSSFeed{}, err
+		return nil, err
+	}
+
+	feed := &Feed{
+		Title:       rssFeed.Channel.Title,
+		Link:        rssFeed.Channel.Link,
+		Description: rssFeed.Channel.Description,
+	}
+
+	for _, item := range rssFeed.Channel.Item {
+		feed.Items = append(feed.Items, Item{
+			GUID:        item.GUID,
+			Title:       item.Title,
+			Link:        item.Link,
+			Description: item.Description,
+			PubDate:     item.PubDate,
+			Author:      item.Author,
+		})
 	}
 
-	return rssFeed, nil
+	return feed, nil
 }
@@ -0,0 +1,313 @@
+package rss
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const rssBody = `<?xml version="1.0"?>
+<rss version="2.0">
+  <channel>
+    <title>Blog</title>
+    <link>https://blog.example.com</link>
+    <description>A blog</description>
+    <item>
+      <title>Hello</title>
+      <link>https://blog.example.com/hello</link>
+      <description>First post</description>
+      <pubDate>Mon, 02 Jan 2006 15:04:05 +0000</pubDate>
+      <guid>hello-1</guid>
+    </item>
+  </channel>
+</rss>`
+
+const atomBody = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>News</title>
+  <link rel="alternate" href="https://news.example.com"/>
+  <entry>
+    <id>urn:news:1</id>
+    <title>Breaking</title>
+    <link rel="alternate" href="https://news.example.com/breaking"/>
+    <updated>2006-01-02T15:04:05Z</updated>
+    <summary>Something happened</summary>
+  </entry>
+</feed>`
+
+const atomXHTMLBody = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>News</title>
+  <entry>
+    <id>urn:news:2</id>
+    <title>Markup</title>
+    <link href="https://news.example.com/markup"/>
+    <updated>2006-01-02T15:04:05Z</updated>
+    <content type="xhtml">
+      <div xmlns="http://www.w3.org/1999/xhtml"><p>Some <em>rich</em> text</p></div>
+    </content>
+  </entry>
+</feed>`
+
+const jsonBody = `{
+  "version": "https://jsonfeed.org/version/1.1",
+  "title": "Podcast",
+  "home_page_url": "https://podcast.example.com",
+  "items": [
+    {"id": "ep-1", "url": "https://podcast.example.com/1", "title": "Episode 1", "content_text": "Pilot", "date_published": "2006-01-02T15:04:05Z"}
+  ]
+}`
+
+const jsonNumberIDBody = `{
+  "version": "https://jsonfeed.org/version/1.1",
+  "title": "Podcast",
+  "items": [
+    {"id": 1024, "url": "https://podcast.example.com/2", "title": "Episode 2", "content_text": "Sequel"}
+  ]
+}`
+
+func TestFetchFeed(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantTitle   string
+		wantItem    Item
+	}{
+		{
+			name:        "rss",
+			contentType: "application/rss+xml",
+			body:        rssBody,
+			wantTitle:   "Blog",
+			wantItem:    Item{GUID: "hello-1", Title: "Hello", Link: "https://blog.example.com/hello", Description: "First post"},
+		},
+		{
+			name:        "atom",
+			contentType: "application/atom+xml",
+			body:        atomBody,
+			wantTitle:   "News",
+			wantItem:    Item{GUID: "urn:news:1", Title: "Breaking", Link: "https://news.example.com/breaking", Description: "Something happened"},
+		},
+		{
+			name:        "atom xhtml content",
+			contentType: "application/atom+xml",
+			body:        atomXHTMLBody,
+			wantTitle:   "News",
+			wantItem:    Item{GUID: "urn:news:2", Title: "Markup", Link: "https://news.example.com/markup", Description: "<p>Some <em>rich</em> text</p>"},
+		},
+		{
+			name:        "json feed",
+			contentType: "application/feed+json",
+			body:        jsonBody,
+			wantTitle:   "Podcast",
+			wantItem:    Item{GUID: "ep-1", Title: "Episode 1", Link: "https://podcast.example.com/1", Description: "Pilot"},
+		},
+		{
+			name:        "json feed number id",
+			contentType: "application/feed+json",
+			body:        jsonNumberIDBody,
+			wantTitle:   "Podcast",
+			wantItem:    Item{GUID: "1024", Title: "Episode 2", Link: "https://podcast.example.com/2", Description: "Sequel"},
+		},
+		{
+			name:        "sniffed without content type",
+			contentType: "text/plain",
+			body:        atomBody,
+			wantTitle:   "News",
+			wantItem:    Item{GUID: "urn:news:1", Title: "Breaking", Link: "https://news.example.com/breaking", Description: "Something happened"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("User-Agent") != "gator" {
+					t.Errorf("User-Agent = %q, want gator", r.Header.Get("User-Agent"))
+				}
+				w.Header().Set("Content-Type", tt.contentType)
+				w.Header().Set("ETag", `"v1"`)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			feed, err := FetchFeed(context.Background(), server.URL, CacheValidators{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if feed.Title != tt.wantTitle {
+				t.Errorf("title = %q, want %q", feed.Title, tt.wantTitle)
+			}
+			if feed.Cache.ETag != `"v1"` {
+				t.Errorf("etag = %q, want \"v1\"", feed.Cache.ETag)
+			}
+			if len(feed.Items) != 1 {
+				t.Fatalf("got %v items, want 1", len(feed.Items))
+			}
+
+			item := feed.Items[0]
+			if item.GUID != tt.wantItem.GUID || item.Title != tt.wantItem.Title || item.Link != tt.wantItem.Link || item.Description != tt.wantItem.Description {
+				t.Errorf("item = %+v, want %+v", item, tt.wantItem)
+			}
+		})
+	}
+}
+
+func TestFetchFeedNotModified(t *testing.T) {
+	lastModified := "Mon, 02 Jan 2006 15:04:05 GMT"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` && r.Header.Get("If-Modified-Since") == lastModified {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write([]byte(rssBody))
+	}))
+	defer server.Close()
+
+	validators := CacheValidators{ETag: `"v1"`, LastModified: lastModified}
+
+	feed, err := FetchFeed(context.Background(), server.URL, validators)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !feed.NotModified {
+		t.Fatal("feed was not reported as not modified")
+	}
+	if len(feed.Items) != 0 {
+		t.Errorf("got %v items on a 304, want none", len(feed.Items))
+	}
+	// The server didn't resend the validators, the previous ones are kept.
+	if feed.Cache != validators {
+		t.Errorf("cache = %+v, want %+v", feed.Cache, validators)
+	}
+}
+
+func TestFetchFeedErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr string
+	}{
+		{
+			name:    "server error",
+			status:  http.StatusInternalServerError,
+			wantErr: "unexpected status code 500",
+		},
+		{
+			name:    "not found",
+			status:  http.StatusNotFound,
+			wantErr: "unexpected status code 404",
+		},
+		{
+			name:    "unknown format",
+			status:  http.StatusOK,
+			body:    "<html><body>not a feed</body></html>",
+			wantErr: ErrUnknownFormat.Error(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			_, err := FetchFeed(context.Background(), server.URL, CacheValidators{})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// RSS authors are email addresses, the feed an item comes from is written as
+// its source instead.
+func TestWriteSource(t *testing.T) {
+	feed := &Feed{
+		ID:    "urn:gator:test",
+		Title: "alice on gator",
+		Items: []Item{{
+			GUID:        "urn:blog:1",
+			Title:       "Hello",
+			Link:        "https://blog.example.com/hello",
+			PublishedAt: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+			Source:      &Source{Title: "Blog", URL: "https://blog.example.com/rss"},
+		}},
+	}
+
+	tests := []struct {
+		format  string
+		want    []string
+		notWant string
+	}{
+		{
+			format:  FormatRSS,
+			want:    []string{`<source url="https://blog.example.com/rss">Blog</source>`},
+			notWant: "<author>",
+		},
+		{
+			format: FormatAtom,
+			want: []string{
+				`<source>`,
+				`<link href="https://blog.example.com/rss" rel="self"></link>`,
+				`<author>`,
+				`<name>Blog</name>`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var b strings.Builder
+			err := Write(&b, tt.format, feed)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, want := range tt.want {
+				if !strings.Contains(b.String(), want) {
+					t.Errorf("document = %s, want %q", b.String(), want)
+				}
+			}
+			if tt.notWant != "" && strings.Contains(b.String(), tt.notWant) {
+				t.Errorf("document = %s, don't want %q", b.String(), tt.notWant)
+			}
+		})
+	}
+}
+
+// A published file and items known by their guid only have no link, RSS 2.0
+// doesn't allow an empty one.
+func TestWriteWithoutLink(t *testing.T) {
+	feed := &Feed{
+		ID:    "urn:gator:test",
+		Title: "alice on gator",
+		Items: []Item{{
+			GUID:        "urn:blog:1",
+			Title:       "Hello",
+			PublishedAt: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		}},
+	}
+
+	for _, format := range []string{FormatRSS, FormatAtom} {
+		t.Run(format, func(t *testing.T) {
+			var b strings.Builder
+			err := Write(&b, format, feed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(b.String(), "<link") {
+				t.Errorf("document = %s, want no link", b.String())
+			}
+		})
+	}
+}
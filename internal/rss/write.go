@@ -0,0 +1,208 @@
+package rss
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Output formats supported by Write.
+const (
+	FormatRSS  = "rss"
+	FormatAtom = "atom"
+)
+
+type rssOutput struct {
+	XMLName xml.Name `xml:"rss"`
+	Version string   `xml:"version,attr"`
+	Channel struct {
+		Title         string          `xml:"title"`
+		Link          string          `xml:"link,omitempty"`
+		Description   string          `xml:"description"`
+		LastBuildDate string          `xml:"lastBuildDate"`
+		Generator     string          `xml:"generator"`
+		Items         []rssOutputItem `xml:"item"`
+	} `xml:"channel"`
+}
+
+type rssOutputItem struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link,omitempty"`
+	Description string `xml:"description,omitempty"`
+	Author      string `xml:"author,omitempty"`
+	PubDate     string `xml:"pubDate"`
+	GUID        struct {
+		Value       string `xml:",chardata"`
+		IsPermaLink bool   `xml:"isPermaLink,attr"`
+	} `xml:"guid"`
+	Source *struct {
+		Title string `xml:",chardata"`
+		URL   string `xml:"url,attr"`
+	} `xml:"source,omitempty"`
+}
+
+type atomOutput struct {
+	XMLName   xml.Name          `xml:"http://www.w3.org/2005/Atom feed"`
+	ID        string            `xml:"id"`
+	Title     string            `xml:"title"`
+	Subtitle  string            `xml:"subtitle,omitempty"`
+	Updated   string            `xml:"updated"`
+	Generator string            `xml:"generator"`
+	Links     []AtomLink        `xml:"link"`
+	Entries   []atomOutputEntry `xml:"entry"`
+}
+
+type atomOutputEntry struct {
+	ID        string     `xml:"id"`
+	Title     string     `xml:"title"`
+	Links     []AtomLink `xml:"link"`
+	Published string     `xml:"published"`
+	Updated   string     `xml:"updated"`
+	Summary   *atomText  `xml:"summary,omitempty"`
+	Author    *struct {
+		Name string `xml:"name"`
+	} `xml:"author,omitempty"`
+	Source *struct {
+		Title string     `xml:"title"`
+		Links []AtomLink `xml:"link"`
+	} `xml:"source,omitempty"`
+}
+
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// Renders the feed as an RSS 2.0 or Atom 1.0 document. Items are written
+// using PublishedAt, PubDate is only used by the parsers. RSS wants an email
+// address as author so the Source of an item is written as its source
+// element, Atom entries also take its title as author name when they have
+// none.
+func Write(w io.Writer, format string, feed *Feed) error {
+	var doc any
+
+	switch format {
+	case FormatRSS:
+		doc = toRSS(feed)
+	case FormatAtom:
+		doc = toAtom(feed)
+	default:
+		return fmt.Errorf("unknown feed format %q, expected %v or %v", format, FormatRSS, FormatAtom)
+	}
+
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	err = encoder.Encode(doc)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+func toRSS(feed *Feed) rssOutput {
+	var doc rssOutput
+	doc.Version = "2.0"
+	doc.Channel.Title = feed.Title
+	doc.Channel.Link = feed.Link
+	doc.Channel.Description = feed.Description
+	doc.Channel.LastBuildDate = lastUpdate(feed).Format(time.RFC1123Z)
+	doc.Channel.Generator = "gator"
+
+	for _, item := range feed.Items {
+		out := rssOutputItem{
+			Title:       item.Title,
+			Link:        item.Link,
+			Description: item.Description,
+			Author:      item.Author,
+			PubDate:     item.PublishedAt.Format(time.RFC1123Z),
+		}
+		out.GUID.Value = item.GUID
+		out.GUID.IsPermaLink = item.GUID == item.Link
+
+		if item.Source != nil {
+			out.Source = &struct {
+				Title string `xml:",chardata"`
+				URL   string `xml:"url,attr"`
+			}{Title: item.Source.Title, URL: item.Source.URL}
+		}
+
+		doc.Channel.Items = append(doc.Channel.Items, out)
+	}
+
+	return doc
+}
+
+func toAtom(feed *Feed) atomOutput {
+	doc := atomOutput{
+		ID:        feed.ID,
+		Title:     feed.Title,
+		Subtitle:  feed.Description,
+		Updated:   lastUpdate(feed).Format(time.RFC3339),
+		Generator: "gator",
+	}
+
+	if feed.Link != "" {
+		doc.Links = []AtomLink{{Href: feed.Link, Rel: "self"}}
+	}
+
+	for _, item := range feed.Items {
+		entry := atomOutputEntry{
+			ID:        item.GUID,
+			Title:     item.Title,
+			Published: item.PublishedAt.Format(time.RFC3339),
+			Updated:   item.PublishedAt.Format(time.RFC3339),
+		}
+
+		if item.Link != "" {
+			entry.Links = []AtomLink{{Href: item.Link, Rel: "alternate"}}
+		}
+
+		if item.Description != "" {
+			entry.Summary = &atomText{Type: "html", Value: item.Description}
+		}
+		author := item.Author
+		if item.Source != nil {
+			entry.Source = &struct {
+				Title string     `xml:"title"`
+				Links []AtomLink `xml:"link"`
+			}{Title: item.Source.Title, Links: []AtomLink{{Href: item.Source.URL, Rel: "self"}}}
+
+			if author == "" {
+				author = item.Source.Title
+			}
+		}
+		if author != "" {
+			entry.Author = &struct {
+				Name string `xml:"name"`
+			}{Name: author}
+		}
+
+		doc.Entries = append(doc.Entries, entry)
+	}
+
+	return doc
+}
+
+// Publication date of the newest item, or now for an empty feed.
+func lastUpdate(feed *Feed) time.Time {
+	updated := time.Time{}
+	for _, item := range feed.Items {
+		if item.PublishedAt.After(updated) {
+			updated = item.PublishedAt
+		}
+	}
+
+	if updated.IsZero() {
+		return time.Now().UTC()
+	}
+
+	return updated.UTC()
+}
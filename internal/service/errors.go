@@ -0,0 +1,74 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/Alb3G/gator/internal/database"
+	"github.com/lib/pq"
+)
+
+// Kinds of domain errors returned by the services, check them with
+// errors.Is to map errors to exit messages or HTTP status codes.
+var (
+	ErrNotFound      = errors.New("not found")
+	ErrAlreadyExists = errors.New("already exists")
+	ErrInvalidInput  = errors.New("invalid input")
+	ErrUnauthorized  = errors.New("unauthorized")
+	ErrForbidden     = errors.New("forbidden")
+)
+
+// Error is a domain error of one of the kinds above with a message meant to
+// be shown to the user.
+type Error struct {
+	Kind    error
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+func notFound(format string, args ...any) error {
+	return &Error{Kind: ErrNotFound, Message: fmt.Sprintf(format, args...)}
+}
+
+func alreadyExists(format string, args ...any) error {
+	return &Error{Kind: ErrAlreadyExists, Message: fmt.Sprintf(format, args...)}
+}
+
+func invalidInput(format string, args ...any) error {
+	return &Error{Kind: ErrInvalidInput, Message: fmt.Sprintf(format, args...)}
+}
+
+func unauthorized(format string, args ...any) error {
+	return &Error{Kind: ErrUnauthorized, Message: fmt.Sprintf(format, args...)}
+}
+
+func forbidden(format string, args ...any) error {
+	return &Error{Kind: ErrForbidden, Message: fmt.Sprintf(format, args...)}
+}
+
+// Translates the database errors that have a domain meaning, resource names
+// what was being looked up or written. Any other error is returned as is.
+func dbError(err error, resource string) error {
+	var pqErr *pq.Error
+
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, sql.ErrNoRows):
+		return notFound("%v not found", resource)
+	case errors.Is(err, database.ErrUniqueViolation), errors.As(err, &pqErr) && pqErr.Code == "23505":
+		return alreadyExists("%v already exists", resource)
+	case errors.Is(err, database.ErrForeignKeyViolation), errors.As(err, &pqErr) && pqErr.Code == "23503":
+		return notFound("%v references a missing record", resource)
+	default:
+		return err
+	}
+}
@@ -0,0 +1,235 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Alb3G/gator/internal/database"
+	"github.com/Alb3G/gator/internal/opml"
+	utils "github.com/Alb3G/gator/internal/utils"
+	"github.com/google/uuid"
+)
+
+type FeedService struct {
+	q database.TxQuerier
+}
+
+func NewFeedService(q database.TxQuerier) *FeedService {
+	return &FeedService{q: q}
+}
+
+// Runs fn with a copy of the service whose queries all run in a single
+// transaction, rolled back when fn returns an error.
+func (s *FeedService) inTx(ctx context.Context, fn func(tx *FeedService) error) error {
+	return s.q.InTx(ctx, func(q database.TxQuerier) error {
+		return fn(&FeedService{q: q})
+	})
+}
+
+// Summary of an OPML import.
+type ImportResult struct {
+	Total           int
+	Created         int
+	Followed        int
+	AlreadyFollowed int
+}
+
+// Creates a feed and follows it on behalf of the user adding it.
+func (s *FeedService) Add(ctx context.Context, user database.User, name, url string) (database.Feed, error) {
+	if name == "" || url == "" {
+		return database.Feed{}, invalidInput("missing required args feed_name or url")
+	}
+
+	var feed database.Feed
+
+	// A feed nobody follows would be left behind if the follow failed.
+	err := s.inTx(ctx, func(tx *FeedService) error {
+		var err error
+		feed, err = tx.q.CreateFeed(ctx, database.CreateFeedParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC(),
+			UpdatedAt: time.Now().UTC(),
+			Name:      name,
+			Url:       url,
+			UserID:    user.ID,
+		})
+		if err != nil {
+			return dbError(err, "feed "+url)
+		}
+
+		_, err = tx.follow(ctx, user, feed, "")
+		return err
+	})
+	if err != nil {
+		return database.Feed{}, err
+	}
+
+	return feed, nil
+}
+
+func (s *FeedService) List(ctx context.Context) ([]database.Feed, error) {
+	return s.q.GetFeeds(ctx)
+}
+
+func (s *FeedService) GetByURL(ctx context.Context, url string) (database.Feed, error) {
+	feed, err := s.q.GetFeedByURL(ctx, url)
+	if err != nil {
+		return database.Feed{}, dbError(err, "feed "+url)
+	}
+
+	return feed, nil
+}
+
+// Feeds whose last fetches failed, most failing first.
+func (s *FeedService) Failing(ctx context.Context) ([]database.Feed, error) {
+	return s.q.GetFailingFeeds(ctx)
+}
+
+func (s *FeedService) Follow(ctx context.Context, user database.User, url, category string) (database.CreateFeedFollowRow, error) {
+	feed, err := s.GetByURL(ctx, url)
+	if err != nil {
+		return database.CreateFeedFollowRow{}, err
+	}
+
+	return s.follow(ctx, user, feed, category)
+}
+
+func (s *FeedService) follow(ctx context.Context, user database.User, feed database.Feed, category string) (database.CreateFeedFollowRow, error) {
+	follow, err := s.q.CreateFeedFollow(ctx, database.CreateFeedFollowParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+		UserID:    user.ID,
+		FeedID:    feed.ID,
+		Category:  utils.NullString(category),
+	})
+	if err != nil {
+		return database.CreateFeedFollowRow{}, dbError(err, "follow of "+feed.Url)
+	}
+
+	return follow, nil
+}
+
+func (s *FeedService) Unfollow(ctx context.Context, user database.User, url string) error {
+	feed, err := s.GetByURL(ctx, url)
+	if err != nil {
+		return err
+	}
+
+	return s.q.DeleteFeedFollow(ctx, database.DeleteFeedFollowParams{UserID: user.ID, FeedID: feed.ID})
+}
+
+func (s *FeedService) Following(ctx context.Context, user database.User) ([]database.GetFeedFollowsByUserRow, error) {
+	return s.q.GetFeedFollowsByUser(ctx, user.ID)
+}
+
+// Looks for a feed followed by the user matching either its url or its name.
+func (s *FeedService) FindFollowed(ctx context.Context, user database.User, urlOrName string) (uuid.UUID, error) {
+	follows, err := s.q.GetFeedFollowsByUser(ctx, user.ID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	for _, follow := range follows {
+		if follow.FeedUrl == urlOrName || follow.FeedName == urlOrName {
+			return follow.FeedID, nil
+		}
+	}
+
+	return uuid.Nil, notFound("you don't follow any feed with url or name %q", urlOrName)
+}
+
+// Follows every subscription not followed yet, creating the feeds that don't
+// exist. The import runs in a single transaction, a failing subscription
+// rolls back the whole import.
+func (s *FeedService) Import(ctx context.Context, user database.User, subscriptions []opml.Subscription) (ImportResult, error) {
+	var result ImportResult
+
+	err := s.inTx(ctx, func(tx *FeedService) error {
+		result = ImportResult{Total: len(subscriptions)}
+
+		follows, err := tx.q.GetFeedFollowsByUser(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+
+		followed := map[string]bool{}
+		for _, follow := range follows {
+			followed[follow.FeedUrl] = true
+		}
+
+		for _, sub := range subscriptions {
+			if followed[sub.URL] {
+				result.AlreadyFollowed++
+				continue
+			}
+
+			created, err := tx.importSubscription(ctx, user, sub)
+			if err != nil {
+				return fmt.Errorf("importing %v: %w", sub.URL, err)
+			}
+
+			if created {
+				result.Created++
+			}
+			result.Followed++
+			followed[sub.URL] = true
+		}
+
+		return nil
+	})
+	if err != nil {
+		return ImportResult{}, err
+	}
+
+	return result, nil
+}
+
+// Follows the subscription, creating its feed first when it doesn't exist.
+func (s *FeedService) importSubscription(ctx context.Context, user database.User, sub opml.Subscription) (bool, error) {
+	created := false
+
+	feed, err := s.GetByURL(ctx, sub.URL)
+	if errors.Is(err, ErrNotFound) {
+		feed, err = s.q.CreateFeed(ctx, database.CreateFeedParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC(),
+			UpdatedAt: time.Now().UTC(),
+			Name:      sub.Title,
+			Url:       sub.URL,
+			UserID:    user.ID,
+		})
+		created = true
+	}
+	if err != nil {
+		return false, err
+	}
+
+	_, err = s.follow(ctx, user, feed, sub.Category)
+	if err != nil {
+		return false, err
+	}
+
+	return created, nil
+}
+
+// Subscriptions of the user, ready to be written as OPML.
+func (s *FeedService) Export(ctx context.Context, user database.User) ([]opml.Subscription, error) {
+	follows, err := s.q.GetFeedFollowsByUser(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	subscriptions := []opml.Subscription{}
+	for _, follow := range follows {
+		subscriptions = append(subscriptions, opml.Subscription{
+			Title:    follow.FeedName,
+			URL:      follow.FeedUrl,
+			Category: follow.Category.String,
+		})
+	}
+
+	return subscriptions, nil
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Alb3G/gator/internal/database"
+	"github.com/Alb3G/gator/internal/fakedb"
+	"github.com/Alb3G/gator/internal/opml"
+)
+
+var errInjected = errors.New("injected failure")
+
+// Wraps a querier to fail the follows of a url and the posts with a url,
+// inside transactions as well.
+type failingQuerier struct {
+	database.TxQuerier
+	followURL string
+	postURL   string
+	feeds     map[string]string
+}
+
+func (q failingQuerier) InTx(ctx context.Context, fn func(database.TxQuerier) error) error {
+	return q.TxQuerier.InTx(ctx, func(tx database.TxQuerier) error {
+		q.TxQuerier = tx
+		return fn(q)
+	})
+}
+
+func (q failingQuerier) CreateFeed(ctx context.Context, arg database.CreateFeedParams) (database.Feed, error) {
+	q.feeds[arg.ID.String()] = arg.Url
+	return q.TxQuerier.CreateFeed(ctx, arg)
+}
+
+func (q failingQuerier) CreateFeedFollow(ctx context.Context, arg database.CreateFeedFollowParams) (database.CreateFeedFollowRow, error) {
+	if q.feeds[arg.FeedID.String()] == q.followURL {
+		return database.CreateFeedFollowRow{}, errInjected
+	}
+	return q.TxQuerier.CreateFeedFollow(ctx, arg)
+}
+
+func (q failingQuerier) UpsertPost(ctx context.Context, arg database.UpsertPostParams) (bool, error) {
+	if arg.Url == q.postURL {
+		return false, errInjected
+	}
+	return q.TxQuerier.UpsertPost(ctx, arg)
+}
+
+func TestAddRollsBackWhenFollowFails(t *testing.T) {
+	ctx := context.Background()
+	db := fakedb.New()
+	q := failingQuerier{TxQuerier: db, followURL: "https://broken.example.com/rss", feeds: map[string]string{}}
+
+	user, err := NewUserService(db).Register(ctx, "alice", "alice-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = NewFeedService(q).Add(ctx, user, "Broken", "https://broken.example.com/rss")
+	if !errors.Is(err, errInjected) {
+		t.Fatalf("add error = %v, want the follow failure", err)
+	}
+
+	feeds, _ := db.GetFeeds(ctx)
+	if len(feeds) != 0 {
+		t.Errorf("feeds after a failed add = %+v, want none", feeds)
+	}
+}
+
+func TestImportRollsBackWhenASubscriptionFails(t *testing.T) {
+	ctx := context.Background()
+	db := fakedb.New()
+	q := failingQuerier{TxQuerier: db, followURL: "https://broken.example.com/rss", feeds: map[string]string{}}
+
+	user, err := NewUserService(db).Register(ctx, "alice", "alice-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = NewFeedService(q).Import(ctx, user, []opml.Subscription{
+		{Title: "Blog", URL: "https://blog.example.com/rss"},
+		{Title: "Broken", URL: "https://broken.example.com/rss"},
+	})
+	if !errors.Is(err, errInjected) || !strings.Contains(err.Error(), "https://broken.example.com/rss") {
+		t.Fatalf("import error = %v, want the follow failure of the broken feed", err)
+	}
+
+	feeds, _ := db.GetFeeds(ctx)
+	follows, _ := db.GetFeedFollows(ctx)
+	if len(feeds) != 0 || len(follows) != 0 {
+		t.Errorf("feeds after a failed import = %+v, follows = %+v, want none", feeds, follows)
+	}
+}
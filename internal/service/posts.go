@@ -0,0 +1,238 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Alb3G/gator/internal/database"
+	rss "github.com/Alb3G/gator/internal/rss"
+	utils "github.com/Alb3G/gator/internal/utils"
+	"github.com/google/uuid"
+)
+
+type PostService struct {
+	q     database.TxQuerier
+	feeds *FeedService
+}
+
+func NewPostService(q database.TxQuerier, feeds *FeedService) *PostService {
+	return &PostService{q: q, feeds: feeds}
+}
+
+// Filters and pagination of Browse, the zero value returns the newest posts
+// of every followed feed.
+type BrowseOptions struct {
+	// Url or name of one of the followed feeds.
+	Feed     string
+	Category string
+	Since    sql.NullTime
+	Until    sql.NullTime
+	Unread   bool
+	Starred  bool
+	Cursor   *Cursor
+	Limit    int32
+	Offset   int32
+}
+
+// Position after the last post of a page. Posts are sorted by publication
+// date and id, which is what the cursor holds.
+type Cursor struct {
+	PublishedAt time.Time
+	ID          uuid.UUID
+}
+
+func CursorAfter(post database.Post) Cursor {
+	return Cursor{PublishedAt: post.PublishedAt, ID: post.ID}
+}
+
+func (c Cursor) String() string {
+	raw := c.PublishedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func ParseCursor(cursor string) (Cursor, error) {
+	invalid := invalidInput("invalid cursor %q", cursor)
+
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return Cursor{}, invalid
+	}
+
+	publishedAt, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return Cursor{}, invalid
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, publishedAt)
+	if err != nil {
+		return Cursor{}, invalid
+	}
+
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		return Cursor{}, invalid
+	}
+
+	return Cursor{PublishedAt: t, ID: parsedID}, nil
+}
+
+// Posts of the feeds followed by the user, newest first.
+func (s *PostService) Browse(ctx context.Context, user database.User, opts BrowseOptions) ([]database.Post, error) {
+	params := database.GetPostsByUserParams{
+		UserID:      user.ID,
+		Since:       opts.Since,
+		Until:       opts.Until,
+		UnreadOnly:  opts.Unread,
+		StarredOnly: opts.Starred,
+		Category:    utils.NullString(opts.Category),
+		Limit:       opts.Limit,
+		Offset:      opts.Offset,
+	}
+
+	if opts.Feed != "" {
+		feedID, err := s.feeds.FindFollowed(ctx, user, opts.Feed)
+		if err != nil {
+			return nil, err
+		}
+		params.FeedID = uuid.NullUUID{UUID: feedID, Valid: true}
+	}
+
+	if opts.Cursor != nil {
+		params.CursorPublishedAt = sql.NullTime{Time: opts.Cursor.PublishedAt, Valid: true}
+		params.CursorID = uuid.NullUUID{UUID: opts.Cursor.ID, Valid: true}
+	}
+
+	return s.q.GetPostsByUser(ctx, params)
+}
+
+func (s *PostService) Search(ctx context.Context, user database.User, query string, limit int32) ([]database.SearchPostsByUserRow, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, invalidInput("missing query arg")
+	}
+
+	return s.q.SearchPostsByUser(ctx, database.SearchPostsByUserParams{
+		Query:  query,
+		UserID: user.ID,
+		Limit:  limit,
+	})
+}
+
+func (s *PostService) Get(ctx context.Context, id uuid.UUID) (database.Post, error) {
+	post, err := s.q.GetPostById(ctx, id)
+	if err != nil {
+		return database.Post{}, dbError(err, fmt.Sprintf("post %v", id))
+	}
+
+	return post, nil
+}
+
+func (s *PostService) MarkRead(ctx context.Context, user database.User, postID uuid.UUID) (database.Post, error) {
+	return s.updateState(ctx, user, postID, func(now sql.NullTime) error {
+		return s.q.MarkPostRead(ctx, database.MarkPostReadParams{UserID: user.ID, PostID: postID, ReadAt: now})
+	})
+}
+
+func (s *PostService) MarkUnread(ctx context.Context, user database.User, postID uuid.UUID) (database.Post, error) {
+	return s.updateState(ctx, user, postID, func(sql.NullTime) error {
+		return s.q.MarkPostUnread(ctx, database.MarkPostUnreadParams{UserID: user.ID, PostID: postID})
+	})
+}
+
+func (s *PostService) Star(ctx context.Context, user database.User, postID uuid.UUID) (database.Post, error) {
+	return s.updateState(ctx, user, postID, func(now sql.NullTime) error {
+		return s.q.StarPost(ctx, database.StarPostParams{UserID: user.ID, PostID: postID, StarredAt: now})
+	})
+}
+
+func (s *PostService) Unstar(ctx context.Context, user database.User, postID uuid.UUID) (database.Post, error) {
+	return s.updateState(ctx, user, postID, func(sql.NullTime) error {
+		return s.q.UnstarPost(ctx, database.UnstarPostParams{UserID: user.ID, PostID: postID})
+	})
+}
+
+// Runs update on the state of a post of a feed the user follows. The posts of
+// other feeds are not found, like those the user can't browse.
+func (s *PostService) updateState(ctx context.Context, user database.User, postID uuid.UUID, update func(now sql.NullTime) error) (database.Post, error) {
+	post, err := s.Get(ctx, postID)
+	if err != nil {
+		return database.Post{}, err
+	}
+
+	follows, err := s.q.GetFeedFollowsByUser(ctx, user.ID)
+	if err != nil {
+		return database.Post{}, err
+	}
+
+	followed := false
+	for _, follow := range follows {
+		if follow.FeedID == post.FeedID {
+			followed = true
+		}
+	}
+	if !followed {
+		return database.Post{}, notFound("post %v not found", postID)
+	}
+
+	err = update(sql.NullTime{Time: time.Now().UTC(), Valid: true})
+	if err != nil {
+		return database.Post{}, err
+	}
+
+	return post, nil
+}
+
+// Builds the aggregated feed of a user out of the posts of the feeds they
+// follow, the same posts shown by Browse. An empty category means every
+// followed feed. Every item is credited to the feed it comes from, and the
+// guids given by the feeds are prefixed with its id so two feeds numbering
+// their items alike don't collide in readers.
+func (s *PostService) AggregatedFeed(ctx context.Context, user database.User, category string, limit int32) (*rss.Feed, error) {
+	follows, err := s.q.GetFeedFollowsByUser(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	sources := map[uuid.UUID]*rss.Source{}
+	for _, follow := range follows {
+		sources[follow.FeedID] = &rss.Source{Title: follow.FeedName, URL: follow.FeedUrl}
+	}
+
+	posts, err := s.Browse(ctx, user, BrowseOptions{Category: category, Limit: limit})
+	if err != nil {
+		return nil, err
+	}
+
+	feed := &rss.Feed{
+		ID:          fmt.Sprintf("urn:gator:%v", user.ID),
+		Title:       fmt.Sprintf("%v on gator", user.UserName),
+		Description: fmt.Sprintf("Posts of the feeds followed by %v", user.UserName),
+	}
+
+	if category != "" {
+		feed.ID = fmt.Sprintf("%v:%v", feed.ID, category)
+		feed.Title = fmt.Sprintf("%v: %v", feed.Title, category)
+		feed.Description = fmt.Sprintf("%v in %v", feed.Description, category)
+	}
+
+	for _, post := range posts {
+		guid := fmt.Sprintf("urn:uuid:%v", post.ID)
+		if post.Guid.String != "" {
+			guid = fmt.Sprintf("urn:gator:%v:%v", post.FeedID, post.Guid.String)
+		}
+
+		feed.Items = append(feed.Items, rss.Item{
+			GUID:        guid,
+			Title:       post.Title,
+			Link:        post.Url,
+			Description: post.Description.String,
+			PublishedAt: post.PublishedAt,
+			Source:      sources[post.FeedID],
+		})
+	}
+
+	return feed, nil
+}
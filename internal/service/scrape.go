@@ -0,0 +1,351 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Alb3G/gator/internal/database"
+	rss "github.com/Alb3G/gator/internal/rss"
+	utils "github.com/Alb3G/gator/internal/utils"
+	uuid "github.com/google/uuid"
+)
+
+// Failing feeds are retried after minFeedBackoff, doubling on every
+// consecutive failure up to maxFeedBackoff.
+const (
+	minFeedBackoff = 5 * time.Minute
+	maxFeedBackoff = 24 * time.Hour
+)
+
+type postStatus int
+
+const (
+	postNew postStatus = iota
+	postUpdated
+	postUnchanged
+)
+
+// Summary of a scrape, printed by agg after every tick.
+type ScrapeResult struct {
+	Feeds     int
+	Fetched   int
+	Inserted  int
+	Updated   int
+	Unchanged int
+	Skipped   int
+	Errors    []error
+	Duration  time.Duration
+}
+
+// Items without a link nor a guid can't be told apart from each other.
+var errNoLinkOrGUID = errors.New("item has no link and no guid")
+
+// An error ingesting a single item, the rest of the feed is still ingested.
+type ItemError struct {
+	Feed string
+	Link string
+	Err  error
+}
+
+func (e *ItemError) Error() string {
+	return fmt.Sprintf("feed %v, item %v: %v", e.Feed, e.Link, e.Err)
+}
+
+func (e *ItemError) Unwrap() error {
+	return e.Err
+}
+
+func (r *ScrapeResult) count(status postStatus) {
+	switch status {
+	case postNew:
+		r.Inserted++
+	case postUpdated:
+		r.Updated++
+	case postUnchanged:
+		r.Unchanged++
+	}
+}
+
+func (r *ScrapeResult) add(other ScrapeResult) {
+	r.Feeds += other.Feeds
+	r.Fetched += other.Fetched
+	r.Inserted += other.Inserted
+	r.Updated += other.Updated
+	r.Unchanged += other.Unchanged
+	r.Skipped += other.Skipped
+	r.Errors = append(r.Errors, other.Errors...)
+}
+
+func (r ScrapeResult) String() string {
+	return fmt.Sprintf(
+		"%v feeds, %v items fetched: %v new, %v updated, %v unchanged, %v skipped, %v errors in %v",
+		r.Feeds, r.Fetched, r.Inserted, r.Updated, r.Unchanged, r.Skipped, len(r.Errors), r.Duration.Round(time.Millisecond),
+	)
+}
+
+// Claims the next batch of stale feeds and scrapes them in parallel using
+// at most concurrency goroutines. Claiming uses FOR UPDATE SKIP LOCKED so
+// several agg processes can share the same database.
+//
+// Cancelling ctx stops scraping: nothing is claimed anymore and the feeds in
+// flight are finished, bounded by their own timeouts. The claimed feeds not
+// started yet keep the last_fetched_at set by the claim, they are still due
+// but a later run only picks them after the feeds that weren't claimed.
+func (s *FeedService) Scrape(ctx context.Context, concurrency, batchSize int) (ScrapeResult, error) {
+	start := time.Now()
+	var result ScrapeResult
+
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
+
+	claimCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	feedsParams := database.GetNextFeedsToFetchParams{
+		LastFetchedAt: sql.NullTime{
+			Time:  time.Now().UTC(),
+			Valid: true,
+		},
+		UpdatedAt: time.Now().UTC(),
+		Limit:     int32(batchSize),
+	}
+
+	feeds, err := s.q.GetNextFeedsToFetch(claimCtx, feedsParams)
+	if err != nil {
+		return result, err
+	}
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+
+	inFlight := context.WithoutCancel(ctx)
+
+feeds:
+	for _, feed := range feeds {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			break feeds
+		}
+		if ctx.Err() != nil {
+			<-sem
+			break
+		}
+
+		wg.Add(1)
+		go func(feed database.Feed) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			feedResult, err := s.scrapeFeed(inFlight, feed)
+			if err != nil {
+				err = s.recordFailure(inFlight, feed, err)
+				log.Printf("Error scraping feed %v: %v", feed.Name, err)
+				feedResult.Errors = append(feedResult.Errors, fmt.Errorf("feed %v: %w", feed.Name, err))
+			}
+
+			mu.Lock()
+			result.add(feedResult)
+			mu.Unlock()
+		}(feed)
+	}
+
+	wg.Wait()
+	result.Duration = time.Since(start)
+
+	return result, nil
+}
+
+// Scrapes a single feed. Errors fetching the feed itself are returned, items
+// with a date that can't be parsed or with neither a link nor a guid are
+// logged and collected in the result instead so one bad item doesn't prevent
+// the rest of the feed from being ingested. The date is optional in RSS, an
+// item without one is dated by the fetch that first sees it. The feed is ingested in a single
+// transaction, a database error on any item rolls back every post written for
+// it along with its fetch.
+func (s *FeedService) scrapeFeed(ctx context.Context, dbFeed database.Feed) (ScrapeResult, error) {
+	result := ScrapeResult{Feeds: 1}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	validators := rss.CacheValidators{
+		ETag:         dbFeed.Etag.String,
+		LastModified: dbFeed.LastModified.String,
+	}
+
+	feed, err := rss.FetchFeed(ctx, dbFeed.Url, validators)
+	if err != nil {
+		return result, err
+	}
+
+	fetchedAt := time.Now().UTC()
+
+	feedFetchedParams := database.MarkFeedFetchedParams{
+		LastFetchedAt: sql.NullTime{
+			Time:  fetchedAt,
+			Valid: true,
+		},
+		UpdatedAt:    time.Now().UTC(),
+		Etag:         utils.NullString(feed.Cache.ETag),
+		LastModified: utils.NullString(feed.Cache.LastModified),
+		ID:           dbFeed.ID,
+	}
+
+	var ingested ScrapeResult
+
+	err = s.inTx(ctx, func(tx *FeedService) error {
+		err := tx.q.MarkFeedFetched(ctx, feedFetchedParams)
+		if err != nil {
+			return err
+		}
+
+		if feed.NotModified {
+			return nil
+		}
+
+		ingested.Fetched = len(feed.Items)
+
+		for _, item := range feed.Items {
+			pubDate := fetchedAt
+			var err error
+			if date := strings.TrimSpace(item.PubDate); date != "" {
+				pubDate, err = utils.ParsePublishedDate(date)
+			}
+			if item.Link == "" && item.GUID == "" {
+				err = errNoLinkOrGUID
+			}
+			if err != nil {
+				itemErr := &ItemError{Feed: dbFeed.Name, Link: item.Link, Err: err}
+				log.Printf("Error ingesting item: %v", itemErr)
+				ingested.Errors = append(ingested.Errors, itemErr)
+				ingested.Skipped++
+				continue
+			}
+
+			status, err := tx.ingestPost(ctx, dbFeed.ID, item, pubDate)
+			if err != nil {
+				return &ItemError{Feed: dbFeed.Name, Link: item.Link, Err: err}
+			}
+
+			ingested.count(status)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	result.add(ingested)
+
+	return result, nil
+}
+
+// Records a failed fetch and schedules the next attempt using exponential
+// backoff. The original error is returned so it keeps being reported.
+func (s *FeedService) recordFailure(ctx context.Context, feed database.Feed, fetchErr error) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	failures := int(feed.ConsecutiveFailures) + 1
+
+	failedParams := database.MarkFeedFailedParams{
+		LastError: utils.NullString(fetchErr.Error()),
+		NextFetchAt: sql.NullTime{
+			Time:  time.Now().UTC().Add(feedBackoff(failures)),
+			Valid: true,
+		},
+		UpdatedAt: time.Now().UTC(),
+		ID:        feed.ID,
+	}
+
+	err := s.q.MarkFeedFailed(ctx, failedParams)
+	if err != nil {
+		return errors.Join(fetchErr, err)
+	}
+
+	return fetchErr
+}
+
+func feedBackoff(failures int) time.Duration {
+	backoff := minFeedBackoff
+	for i := 1; i < failures && backoff < maxFeedBackoff; i++ {
+		backoff *= 2
+	}
+
+	return min(backoff, maxFeedBackoff)
+}
+
+// Stores a feed item as a post. Items are matched first by GUID, so an item
+// whose link changed is still recognized, and then by URL within the feed.
+func (s *FeedService) ingestPost(ctx context.Context, feedID uuid.UUID, item rss.Item, pubDate time.Time) (postStatus, error) {
+	description := sql.NullString{
+		String: item.Description,
+		Valid:  true,
+	}
+
+	if item.GUID != "" {
+		existing, err := s.q.GetPostByGUID(ctx, database.GetPostByGUIDParams{
+			FeedID: feedID,
+			Guid:   utils.NullString(item.GUID),
+		})
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return postUnchanged, err
+		}
+
+		if err == nil {
+			if existing.Title == item.Title && existing.Url == item.Link && existing.Description == description {
+				return postUnchanged, nil
+			}
+
+			updateParams := database.UpdatePostParams{
+				Title:       item.Title,
+				Url:         item.Link,
+				Description: description,
+				UpdatedAt:   time.Now().UTC(),
+				ID:          existing.ID,
+			}
+			err = s.q.UpdatePost(ctx, updateParams)
+			if err != nil {
+				return postUnchanged, err
+			}
+
+			return postUpdated, nil
+		}
+	}
+
+	postParams := database.UpsertPostParams{
+		ID:          uuid.New(),
+		CreatedAt:   time.Now().UTC(),
+		UpdatedAt:   time.Now().UTC(),
+		Title:       item.Title,
+		Url:         item.Link,
+		Description: description,
+		PublishedAt: pubDate,
+		FeedID:      feedID,
+		Guid:        utils.NullString(item.GUID),
+	}
+
+	inserted, err := s.q.UpsertPost(ctx, postParams)
+	if errors.Is(err, sql.ErrNoRows) {
+		return postUnchanged, nil
+	}
+	if err != nil {
+		return postUnchanged, err
+	}
+
+	if inserted {
+		return postNew, nil
+	}
+
+	return postUpdated, nil
+}
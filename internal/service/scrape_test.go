@@ -0,0 +1,297 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Alb3G/gator/internal/database"
+	"github.com/Alb3G/gator/internal/fakedb"
+	"github.com/google/uuid"
+)
+
+const scrapeFeedBody = `<?xml version="1.0"?>
+<rss version="2.0">
+  <channel>
+    <title>Blog</title>
+    <item>
+      <title>Hello</title>
+      <link>https://blog.example.com/hello</link>
+      <pubDate>Mon, 02 Jan 2006 15:04:05 +0000</pubDate>
+      <guid>hello</guid>
+    </item>
+    <item>
+      <title>Bad date</title>
+      <link>https://blog.example.com/bad</link>
+      <pubDate>someday</pubDate>
+    </item>
+  </channel>
+</rss>`
+
+func newScrapeFixture(t *testing.T, handler http.HandlerFunc) (*FeedService, *fakedb.DB, database.Feed) {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	db := fakedb.New()
+	users := NewUserService(db)
+	feeds := NewFeedService(db)
+
+	user, err := users.Register(context.Background(), "alice", "alice-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	feed, err := db.CreateFeed(context.Background(), database.CreateFeedParams{ID: uuid.New(), Name: "Blog", Url: server.URL, UserID: user.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return feeds, db, feed
+}
+
+func TestScrape(t *testing.T) {
+	var requests atomic.Int32
+	feeds, db, feed := newScrapeFixture(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(scrapeFeedBody))
+	})
+
+	result, err := feeds.Scrape(context.Background(), 2, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Feeds != 1 || result.Fetched != 2 || result.Inserted != 1 || result.Skipped != 1 || len(result.Errors) != 1 {
+		t.Errorf("first scrape = %v", result)
+	}
+
+	stored, _ := db.GetFeedByURL(context.Background(), feed.Url)
+	if stored.Etag.String != `"v1"` || !stored.LastSuccessAt.Valid {
+		t.Errorf("feed after scrape = %+v", stored)
+	}
+
+	// The feed was just fetched, claim it again as if it had gone stale.
+	result, err = feeds.Scrape(context.Background(), 2, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if requests.Load() != 2 || result.Feeds != 1 || result.Fetched != 0 {
+		t.Errorf("second scrape = %v after %v requests, want a 304", result, requests.Load())
+	}
+}
+
+func TestScrapeRecordsFailures(t *testing.T) {
+	feeds, db, feed := newScrapeFixture(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+
+	result, err := feeds.Scrape(context.Background(), 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Errors) != 1 {
+		t.Fatalf("errors = %v, want the fetch error", result.Errors)
+	}
+
+	stored, _ := db.GetFeedByURL(context.Background(), feed.Url)
+	if stored.ConsecutiveFailures != 1 || !stored.LastError.Valid || !stored.NextFetchAt.Valid {
+		t.Errorf("feed after failure = %+v", stored)
+	}
+
+	// The backoff keeps the feed from being claimed again right away.
+	result, err = feeds.Scrape(context.Background(), 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Feeds != 0 {
+		t.Errorf("failing feed claimed again before its backoff: %v", result)
+	}
+}
+
+func TestScrapeFinishesFeedsInFlight(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	feeds, _, _ := newScrapeFixture(t, func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.Write([]byte(scrapeFeedBody))
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	type scrape struct {
+		result ScrapeResult
+		err    error
+	}
+	done := make(chan scrape)
+	go func() {
+		result, err := feeds.Scrape(ctx, 1, 1)
+		done <- scrape{result, err}
+	}()
+
+	// Stop while the feed is being fetched, it is still ingested.
+	<-started
+	cancel()
+	close(release)
+
+	got := <-done
+	if got.err != nil {
+		t.Fatal(got.err)
+	}
+	if got.result.Fetched != 2 || got.result.Inserted != 1 {
+		t.Errorf("scrape cancelled in flight = %v, want the feed ingested", got.result)
+	}
+
+	// Nothing is claimed once cancelled.
+	_, err := feeds.Scrape(ctx, 1, 1)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("scrape after cancel error = %v, want context.Canceled", err)
+	}
+}
+
+func TestScrapeRollsBackFeedOnDatabaseError(t *testing.T) {
+	_, db, feed := newScrapeFixture(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<?xml version="1.0"?>
+<rss version="2.0">
+  <channel>
+    <title>Blog</title>
+    <item>
+      <title>One</title>
+      <link>https://blog.example.com/one</link>
+      <pubDate>Mon, 02 Jan 2006 15:04:05 +0000</pubDate>
+      <guid>one</guid>
+    </item>
+    <item>
+      <title>Two</title>
+      <link>https://blog.example.com/two</link>
+      <pubDate>Tue, 03 Jan 2006 15:04:05 +0000</pubDate>
+      <guid>two</guid>
+    </item>
+  </channel>
+</rss>`))
+	})
+	feeds := NewFeedService(failingQuerier{TxQuerier: db, postURL: "https://blog.example.com/two"})
+
+	result, err := feeds.Scrape(context.Background(), 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Inserted != 0 || len(result.Errors) != 1 || !errors.Is(result.Errors[0], errInjected) {
+		t.Errorf("scrape = %v with errors %v, want the feed failed", result, result.Errors)
+	}
+
+	_, err = db.GetPostByGUID(context.Background(), database.GetPostByGUIDParams{FeedID: feed.ID, Guid: sql.NullString{String: "one", Valid: true}})
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("first post lookup error = %v, want it rolled back", err)
+	}
+
+	stored, _ := db.GetFeedByURL(context.Background(), feed.Url)
+	if stored.LastSuccessAt.Valid || stored.ConsecutiveFailures != 1 {
+		t.Errorf("feed after rollback = %+v, want a failed fetch", stored)
+	}
+}
+
+func TestScrapeSkipsItemsWithoutLinkNorGUID(t *testing.T) {
+	feeds, db, feed := newScrapeFixture(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<?xml version="1.0"?>
+<rss version="2.0">
+  <channel>
+    <title>Blog</title>
+    <item>
+      <title>Linked by guid</title>
+      <pubDate>Mon, 02 Jan 2006 15:04:05 +0000</pubDate>
+      <guid>one</guid>
+    </item>
+    <item>
+      <title>Also linked by guid</title>
+      <pubDate>Mon, 02 Jan 2006 15:04:05 +0000</pubDate>
+      <guid>two</guid>
+    </item>
+    <item>
+      <title>Nothing to tell it apart</title>
+      <pubDate>Mon, 02 Jan 2006 15:04:05 +0000</pubDate>
+    </item>
+  </channel>
+</rss>`))
+	})
+
+	result, err := feeds.Scrape(context.Background(), 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Inserted != 2 || result.Skipped != 1 || len(result.Errors) != 1 || !errors.Is(result.Errors[0], errNoLinkOrGUID) {
+		t.Errorf("scrape = %v with errors %v, want the guid-less item skipped", result, result.Errors)
+	}
+
+	for _, guid := range []string{"one", "two"} {
+		post, err := db.GetPostByGUID(context.Background(), database.GetPostByGUIDParams{FeedID: feed.ID, Guid: sql.NullString{String: guid, Valid: true}})
+		if err != nil || post.Url != "" {
+			t.Errorf("post %v = %+v, %v", guid, post, err)
+		}
+	}
+}
+
+func TestScrapeDatesItemsWithoutPubDate(t *testing.T) {
+	feeds, db, _ := newScrapeFixture(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<?xml version="1.0"?>
+<rss version="2.0">
+  <channel>
+    <title>Blog</title>
+    <item>
+      <title>Undated</title>
+      <link>https://blog.example.com/undated</link>
+    </item>
+    <item>
+      <title>Bad date</title>
+      <link>https://blog.example.com/bad</link>
+      <pubDate>someday</pubDate>
+    </item>
+  </channel>
+</rss>`))
+	})
+
+	before := time.Now().UTC()
+	result, err := feeds.Scrape(context.Background(), 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Inserted != 1 || result.Skipped != 1 || len(result.Errors) != 1 || !strings.Contains(result.Errors[0].Error(), "unable to parse date: someday") {
+		t.Errorf("scrape = %v with errors %v, want the undated item ingested and the bad date skipped", result, result.Errors)
+	}
+
+	posts, err := db.GetPosts(context.Background())
+	if err != nil || len(posts) != 1 || posts[0].Url != "https://blog.example.com/undated" {
+		t.Fatalf("posts = %+v, %v, want the undated one", posts, err)
+	}
+	if posts[0].PublishedAt.Before(before) || posts[0].PublishedAt.After(time.Now()) {
+		t.Errorf("undated post published at %v, want the fetch time", posts[0].PublishedAt)
+	}
+
+	// A later fetch doesn't move it to the top of the timeline again.
+	_, err = feeds.Scrape(context.Background(), 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	post, err := db.GetPostById(context.Background(), posts[0].ID)
+	if err != nil || !post.PublishedAt.Equal(posts[0].PublishedAt) {
+		t.Errorf("undated post after a second fetch = %+v, %v, want its first date kept", post, err)
+	}
+}
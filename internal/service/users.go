@@ -0,0 +1,448 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Alb3G/gator/internal/database"
+	utils "github.com/Alb3G/gator/internal/utils"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Bounds of the password length, bcrypt ignores anything past 72 bytes so
+// longer passwords are rejected rather than silently truncated.
+const (
+	minPasswordLength = 8
+	maxPasswordLength = 72
+)
+
+type UserService struct {
+	q database.TxQuerier
+}
+
+func NewUserService(q database.TxQuerier) *UserService {
+	return &UserService{q: q}
+}
+
+// Creates a user, the first one of the database becomes its admin.
+func (s *UserService) Register(ctx context.Context, userName, password string) (database.User, error) {
+	// Add a util function in the future to validate correct userNames
+	if strings.TrimSpace(userName) == "" {
+		return database.User{}, invalidInput("no user name provided")
+	}
+
+	hash, err := hashPassword(password)
+	if err != nil {
+		return database.User{}, err
+	}
+
+	var user database.User
+
+	err = s.q.InTx(ctx, func(q database.TxQuerier) error {
+		users, err := q.GetUsers(ctx)
+		if err != nil {
+			return err
+		}
+
+		user, err = q.CreateUser(ctx, database.CreateUserParams{
+			ID:           uuid.New(),
+			CreatedAt:    utils.Now(),
+			UpdatedAt:    utils.Now(),
+			UserName:     userName,
+			PasswordHash: utils.NullString(hash),
+			IsAdmin:      len(users) == 0,
+		})
+		return dbError(err, "user "+userName)
+	})
+	if err != nil {
+		return database.User{}, err
+	}
+
+	return user, nil
+}
+
+// Checks the password of the user and opens a session for it, returning the
+// token that identifies the session. Users created before passwords existed
+// have none and can't log in until SetPassword or SetFirstPassword gives
+// them one.
+func (s *UserService) Login(ctx context.Context, userName, password string) (database.User, string, error) {
+	user, err := s.GetByName(ctx, userName)
+	if err != nil {
+		return database.User{}, "", err
+	}
+
+	if !user.PasswordHash.Valid {
+		return database.User{}, "", unauthorized("user %v has no password yet, an admin can set it with `gator setpassword %v`", userName, userName)
+	}
+	if !checkPassword(user, password) {
+		return database.User{}, "", unauthorized("wrong password for user %v", userName)
+	}
+
+	token, err := openSession(ctx, s.q, user)
+	if err != nil {
+		return database.User{}, "", err
+	}
+
+	return user, token, nil
+}
+
+// Replaces the password of the user after checking the current one. Every
+// session of the user is closed and a new one is opened for the caller.
+func (s *UserService) ChangePassword(ctx context.Context, user database.User, current, password string) (string, error) {
+	if !checkPassword(user, current) {
+		return "", unauthorized("wrong current password")
+	}
+
+	var token string
+
+	err := s.q.InTx(ctx, func(q database.TxQuerier) error {
+		err := setPassword(ctx, q, user, password)
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteUserSessions(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+
+		token, err = openSession(ctx, q, user)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// Sets the password of a user on behalf of an admin, closing every session
+// of the user.
+func (s *UserService) SetPassword(ctx context.Context, admin database.User, userName, password string) error {
+	err := RequireAdmin(admin)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.GetByName(ctx, userName)
+	if err != nil {
+		return err
+	}
+
+	return s.q.InTx(ctx, func(q database.TxQuerier) error {
+		err := setPassword(ctx, q, user, password)
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteUserSetupTokens(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+
+		return q.DeleteUserSessions(ctx, user.ID)
+	})
+}
+
+// A setup token and the user it lets set their first password.
+type SetupToken struct {
+	UserName string
+	Token    string
+}
+
+// Opens a setup token for every admin without a password, closing their
+// previous one. Admins of databases created before passwords existed have
+// none and nobody could set it otherwise.
+func (s *UserService) NewSetupTokens(ctx context.Context) ([]SetupToken, error) {
+	var tokens []SetupToken
+
+	err := s.q.InTx(ctx, func(q database.TxQuerier) error {
+		users, err := q.GetUsers(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, user := range users {
+			if !user.IsAdmin || user.PasswordHash.Valid {
+				continue
+			}
+
+			err := q.DeleteUserSetupTokens(ctx, user.ID)
+			if err != nil {
+				return err
+			}
+
+			token := rand.Text()
+			err = q.CreateSetupToken(ctx, database.CreateSetupTokenParams{
+				TokenHash: hashToken(token),
+				UserID:    user.ID,
+				CreatedAt: utils.Now(),
+			})
+			if err != nil {
+				return err
+			}
+
+			tokens = append(tokens, SetupToken{UserName: user.UserName, Token: token})
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// Sets the first password of the user named userName when token is their
+// setup token, which is closed.
+func (s *UserService) SetFirstPassword(ctx context.Context, userName, token, password string) error {
+	user, err := s.q.GetUserBySetupToken(ctx, hashToken(token))
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && user.UserName != userName) {
+		return unauthorized("wrong setup token for user %v", userName)
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.q.InTx(ctx, func(q database.TxQuerier) error {
+		err := setPassword(ctx, q, user, password)
+		if err != nil {
+			return err
+		}
+
+		return q.DeleteUserSetupTokens(ctx, user.ID)
+	})
+}
+
+// The user of an open session.
+func (s *UserService) GetBySession(ctx context.Context, token string) (database.User, error) {
+	if token == "" {
+		return database.User{}, unauthorized("not logged in")
+	}
+
+	user, err := s.q.GetUserBySession(ctx, hashToken(token))
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.User{}, unauthorized("the session is closed")
+	}
+	if err != nil {
+		return database.User{}, err
+	}
+
+	return user, nil
+}
+
+// Opens a new feed token for the user, closing the previous one. Feed readers
+// send it to fetch the aggregated feed of the user, it grants nothing else.
+func (s *UserService) NewFeedToken(ctx context.Context, user database.User) (string, error) {
+	token := rand.Text()
+
+	err := s.q.InTx(ctx, func(q database.TxQuerier) error {
+		err := q.DeleteUserFeedTokens(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+
+		return q.CreateFeedToken(ctx, database.CreateFeedTokenParams{
+			TokenHash: hashToken(token),
+			UserID:    user.ID,
+			CreatedAt: utils.Now(),
+		})
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// The user named userName when token is their feed token.
+func (s *UserService) GetByFeedToken(ctx context.Context, userName, token string) (database.User, error) {
+	if token == "" {
+		return database.User{}, unauthorized("missing feed token")
+	}
+
+	user, err := s.q.GetUserByFeedToken(ctx, hashToken(token))
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && user.UserName != userName) {
+		return database.User{}, unauthorized("wrong feed token for user %v", userName)
+	}
+	if err != nil {
+		return database.User{}, err
+	}
+
+	return user, nil
+}
+
+func (s *UserService) GetByName(ctx context.Context, userName string) (database.User, error) {
+	user, err := s.q.GetUserByName(ctx, userName)
+	if err != nil {
+		return database.User{}, dbError(err, "user "+userName)
+	}
+
+	return user, nil
+}
+
+func (s *UserService) List(ctx context.Context) ([]database.User, error) {
+	return s.q.GetUsers(ctx)
+}
+
+// What Reset deletes, everything when no field is set.
+type ResetScope struct {
+	// Every post, with their read and starred states.
+	Posts bool
+	// Every feed, with their follows and posts.
+	Feeds bool
+	// The user with this name, with their feeds, follows and post states.
+	User string
+}
+
+// Returns a forbidden error unless the user administers the database.
+func RequireAdmin(user database.User) error {
+	if !user.IsAdmin {
+		return forbidden("user %v isn't an admin", user.UserName)
+	}
+
+	return nil
+}
+
+// Deletes the data in scope on behalf of an admin. Deleting users cascades to
+// their feeds, follows and posts. snapshot is called first with a querier on
+// the same transaction so it can save the data about to be deleted, an error
+// from it cancels the reset.
+func (s *UserService) Reset(ctx context.Context, admin database.User, scope ResetScope, snapshot func(database.Querier) error) error {
+	err := RequireAdmin(admin)
+	if err != nil {
+		return err
+	}
+
+	return s.q.InTx(ctx, func(q database.TxQuerier) error {
+		var target database.User
+		if scope.User != "" {
+			target, err = q.GetUserByName(ctx, scope.User)
+			if err != nil {
+				return dbError(err, "user "+scope.User)
+			}
+			if target.ID == admin.ID {
+				return invalidInput("can't delete the logged in user %v", admin.UserName)
+			}
+		}
+
+		err := snapshot(q)
+		if err != nil {
+			return fmt.Errorf("writing the snapshot: %w", err)
+		}
+
+		switch {
+		case scope.Posts:
+			return q.DeletePosts(ctx)
+		case scope.Feeds:
+			return q.DeleteFeeds(ctx)
+		case scope.User != "":
+			return q.DeleteUser(ctx, target.ID)
+		default:
+			return q.Reset(ctx)
+		}
+	})
+}
+
+// Runs write on behalf of an admin with a querier on a transaction, so the
+// archive it reads is consistent.
+func (s *UserService) Backup(ctx context.Context, admin database.User, write func(database.Querier) error) error {
+	err := RequireAdmin(admin)
+	if err != nil {
+		return err
+	}
+
+	return s.q.InTx(ctx, func(q database.TxQuerier) error {
+		return write(q)
+	})
+}
+
+// Replaces every row of the database with the ones restore inserts, on
+// behalf of an admin. Like Reset, snapshot is called first with a querier on
+// the same transaction, then everything is deleted and restore runs. An
+// error from either rolls the whole restore back. The archive brings its own
+// users and admins, sessions are all closed.
+func (s *UserService) Restore(ctx context.Context, admin database.User, snapshot, restore func(database.Querier) error) error {
+	err := RequireAdmin(admin)
+	if err != nil {
+		return err
+	}
+
+	return s.q.InTx(ctx, func(q database.TxQuerier) error {
+		err := snapshot(q)
+		if err != nil {
+			return fmt.Errorf("writing the snapshot: %w", err)
+		}
+
+		err = q.Reset(ctx)
+		if err != nil {
+			return err
+		}
+
+		return restore(q)
+	})
+}
+
+func hashPassword(password string) (string, error) {
+	if len(password) < minPasswordLength {
+		return "", invalidInput("the password must have at least %v characters", minPasswordLength)
+	}
+	if len(password) > maxPasswordLength {
+		return "", invalidInput("the password must have at most %v bytes", maxPasswordLength)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+func checkPassword(user database.User, password string) bool {
+	err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash.String), []byte(password))
+	return err == nil
+}
+
+func setPassword(ctx context.Context, q database.Querier, user database.User, password string) error {
+	hash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	return q.SetUserPassword(ctx, database.SetUserPasswordParams{
+		ID:           user.ID,
+		PasswordHash: utils.NullString(hash),
+		UpdatedAt:    utils.Now(),
+	})
+}
+
+// Only a hash of the token is stored, so the sessions can't be stolen from
+// the database.
+func openSession(ctx context.Context, q database.Querier, user database.User) (string, error) {
+	token := rand.Text()
+
+	err := q.CreateSession(ctx, database.CreateSessionParams{
+		TokenHash: hashToken(token),
+		UserID:    user.ID,
+		CreatedAt: utils.Now(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,85 @@
+// Package storage opens the database configured in db_url, a Postgres
+// connection string or a sqlite:// path, and wires the matching queries and
+// migrations.
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Alb3G/gator/internal/database"
+	"github.com/Alb3G/gator/internal/database/sqlite"
+	"github.com/Alb3G/gator/internal/migrate"
+	"github.com/Alb3G/gator/sql/schema"
+	_ "github.com/lib/pq"
+)
+
+const sqliteScheme = "sqlite://"
+
+type Store struct {
+	DB         *sql.DB
+	Queries    database.TxQuerier
+	Migrations *migrate.Migrator
+}
+
+// Opens the database of dbURL. Postgres is used unless the url starts with
+// sqlite://, followed by the path of the database file: sqlite:///home/me/gator.db
+// for an absolute path or sqlite://~/gator.db for one in the home directory.
+func Open(dbURL string) (*Store, error) {
+	path, ok, err := SQLitePath(dbURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if ok {
+		db, err := sqlite.Open(path)
+		if err != nil {
+			return nil, err
+		}
+
+		return newStore(db, sqlite.New(db), schema.SQLiteMigrations(), migrate.SQLite)
+	}
+
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return newStore(db, database.New(db), schema.Migrations, migrate.Postgres)
+}
+
+func newStore(db *sql.DB, queries database.TxQuerier, migrations fs.FS, dialect migrate.Dialect) (*Store, error) {
+	migrator, err := migrate.New(db, migrations, dialect)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Store{DB: db, Queries: queries, Migrations: migrator}, nil
+}
+
+// Returns the database file of a sqlite:// url, ok is false for any other url.
+func SQLitePath(dbURL string) (path string, ok bool, err error) {
+	path, ok = strings.CutPrefix(dbURL, sqliteScheme)
+	if !ok {
+		return "", false, nil
+	}
+
+	if path == "" {
+		return "", true, errors.New("missing database file path in db_url, e.g. sqlite:///home/me/gator.db")
+	}
+
+	if rest, found := strings.CutPrefix(path, "~/"); found {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", true, err
+		}
+		path = filepath.Join(home, rest)
+	}
+
+	return path, true, nil
+}
@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"database/sql"
 	"fmt"
 	"strconv"
 	"time"
@@ -10,6 +11,20 @@ func Now() time.Time {
 	return time.Now().UTC()
 }
 
+// Returns a valid sql.NullString for non empty strings.
+func NullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// Formats a nullable timestamp, using fallback when it is NULL.
+func FormatNullTime(t sql.NullTime, fallback string) string {
+	if !t.Valid {
+		return fallback
+	}
+
+	return t.Time.Format(time.RFC1123)
+}
+
 func ParsePublishedDate(dateStr string) (time.Time, error) {
 	layouts := []string{
 		time.RFC1123Z, // "Mon, 02 Jan 2006 15:04:05 -0700"
@@ -27,6 +42,22 @@ func ParsePublishedDate(dateStr string) (time.Time, error) {
 	return time.Time{}, fmt.Errorf("unable to parse date: %s", dateStr)
 }
 
+// Parses a date given on the command line, either as YYYY-MM-DD or RFC3339.
+// An empty string is a NULL date.
+func ParseNullDate(dateStr string) (sql.NullTime, error) {
+	if dateStr == "" {
+		return sql.NullTime{}, nil
+	}
+
+	for _, layout := range []string{time.DateOnly, time.RFC3339} {
+		if t, err := time.Parse(layout, dateStr); err == nil {
+			return sql.NullTime{Time: t.UTC(), Valid: true}, nil
+		}
+	}
+
+	return sql.NullTime{}, fmt.Errorf("unable to parse date: %s", dateStr)
+}
+
 func ParseLimit(args []string, defaultLimit int32) int32 {
 	if len(args) < 2 {
 		return defaultLimit
@@ -39,3 +70,18 @@ func ParseLimit(args []string, defaultLimit int32) int32 {
 
 	return int32(i)
 }
+
+// Parses the positive integer at args[index], falling back to defaultValue
+// when it is missing or invalid.
+func ParseIntArg(args []string, index int, defaultValue int) int {
+	if len(args) <= index {
+		return defaultValue
+	}
+
+	i, err := strconv.Atoi(args[index])
+	if err != nil || i <= 0 {
+		return defaultValue
+	}
+
+	return i
+}
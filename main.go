@@ -1,59 +1,79 @@
 package main
 
 import (
-	"database/sql"
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/Alb3G/gator/internal"
 	"github.com/Alb3G/gator/internal/config"
-	"github.com/Alb3G/gator/internal/database"
-	_ "github.com/lib/pq"
+	"github.com/Alb3G/gator/internal/storage"
 )
 
 func main() {
+	cmds := internal.NewCommands()
+
 	args := os.Args
 	if len(args) < 2 {
-		fmt.Println("No enough arguments provided")
+		cmds.PrintUsage(os.Stderr)
 		os.Exit(1)
 	}
 
-	c := config.Read()
+	// Ctrl-C or a systemd stop cancels ctx so the running command can wind
+	// down, a second signal kills gator right away.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
 
-	db, err := sql.Open("postgres", c.DbUrl)
+	cmd := internal.Command{
+		Name: args[1],
+		Args: args[1:],
+	}
+
+	err := run(ctx, cmds, cmd)
+	stop()
 	if err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
+}
 
-	queries := database.New(db)
+func run(ctx context.Context, cmds *internal.Commands, cmd internal.Command) error {
+	spec, ok := cmds.Lookup(cmd.Name)
+	if ok && spec.Offline {
+		return cmds.Run(ctx, nil, cmd)
+	}
 
-	s := config.State{Config: c, Queries: queries}
+	c := config.Read()
 
-	cmds := internal.Commands{
-		AvailableCommands: make(map[string]func(*config.State, internal.Command) error),
+	store, err := storage.Open(c.DbUrl)
+	if err != nil {
+		return err
 	}
+	defer store.DB.Close()
 
-	cmds.Register("login", internal.LoginHandler)
-	cmds.Register("register", internal.RegisterHandler)
-	cmds.Register("reset", internal.ResetHandler)
-	cmds.Register("users", internal.Users)
-	cmds.Register("agg", internal.Agg)
-	cmds.Register("addfeed", internal.MiddlewareLoggedIn(internal.AddFeed))
-	cmds.Register("feeds", internal.MiddlewareLoggedIn(internal.FeedsHandler))
-	cmds.Register("follow", internal.MiddlewareLoggedIn(internal.Follow))
-	cmds.Register("following", internal.MiddlewareLoggedIn(internal.Following))
-	cmds.Register("unfollow", internal.MiddlewareLoggedIn(internal.Unfollow))
-	cmds.Register("browse", internal.MiddlewareLoggedIn(internal.Browse))
+	s := config.NewState(c, store.Queries)
+	s.Migrations = store.Migrations
 
-	cmd := internal.Command{
-		Name: args[1],
-		Args: args[1:],
+	// Commands like migrate work before the schema is up to date.
+	if ok && !spec.SkipSchemaCheck {
+		err = checkSchema(ctx, s)
+		if err != nil {
+			return err
+		}
 	}
 
-	err = cmds.Run(&s, cmd)
-	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
-	}
+	return cmds.Run(ctx, s, cmd)
+}
+
+func checkSchema(ctx context.Context, s *config.State) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	return s.Migrations.Check(ctx)
 }
@@ -1,7 +1,7 @@
 -- name: CreateFeedFollow :one
 WITH inserted_feed_follow AS (
-    INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id)
-	values ($1, $2, $3, $4, $5)
+    INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id, category)
+	values ($1, $2, $3, $4, $5, $6)
     RETURNING *
 )
 
@@ -19,6 +19,7 @@ on inserted_feed_follow.user_id = users.id;
 SELECT 
     feed_follows.*, 
     feeds.name AS feed_name,
+    feeds.url AS feed_url,
     users.user_name AS user_name  
 FROM feed_follows
 INNER JOIN feeds ON feed_follows.feed_id = feeds.id
@@ -26,4 +27,14 @@ INNER JOIN users ON feed_follows.user_id = users.id
 WHERE feed_follows.user_id = $1;
 
 -- name: DeleteFeedFollow :exec
-DELETE FROM feed_follows WHERE user_id = $1 AND feed_id = $2;
\ No newline at end of file
+DELETE FROM feed_follows WHERE user_id = $1 AND feed_id = $2;
+
+-- name: GetFeedFollows :many
+SELECT * FROM feed_follows;
+
+-- name: GetFeedFollowsAfter :many
+SELECT * FROM feed_follows WHERE id > sqlc.arg('after_id') ORDER BY id LIMIT sqlc.arg('limit');
+
+-- name: RestoreFeedFollow :exec
+INSERT INTO feed_follows(id, created_at, updated_at, user_id, feed_id, category)
+VALUES ($1, $2, $3, $4, $5, $6);
\ No newline at end of file
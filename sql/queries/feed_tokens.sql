@@ -0,0 +1,11 @@
+-- name: CreateFeedToken :exec
+INSERT INTO feed_tokens(token_hash, user_id, created_at)
+VALUES ($1, $2, $3);
+
+-- name: GetUserByFeedToken :one
+SELECT users.* FROM users
+JOIN feed_tokens ON feed_tokens.user_id = users.id
+WHERE feed_tokens.token_hash = $1;
+
+-- name: DeleteUserFeedTokens :exec
+DELETE FROM feed_tokens WHERE user_id = $1;
\ No newline at end of file
@@ -5,13 +5,47 @@ VALUES ($1, $2, $3, $4, $5, $6) RETURNING *;
 -- name: GetFeeds :many
 SELECT * FROM feeds;
 
+-- name: GetFeedsAfter :many
+SELECT * FROM feeds WHERE id > sqlc.arg('after_id') ORDER BY id LIMIT sqlc.arg('limit');
+
 -- name: GetFeedByURL :one
 SELECT * FROM feeds WHERE url = $1;
 
 -- name: MarkFeedFetched :exec
 UPDATE feeds 
-SET last_fetched_at = $1, updated_at = $2
-WHERE id = $3;
+SET last_fetched_at = $1, updated_at = $2, etag = $3, last_modified = $4,
+    last_success_at = $1, consecutive_failures = 0, last_error = NULL, next_fetch_at = NULL
+WHERE id = $5;
+
+-- name: MarkFeedFailed :exec
+UPDATE feeds
+SET last_error = $1, consecutive_failures = consecutive_failures + 1, next_fetch_at = $2, updated_at = $3
+WHERE id = $4;
+
+-- name: GetFailingFeeds :many
+SELECT * FROM feeds
+WHERE consecutive_failures > 0
+ORDER BY consecutive_failures DESC, name ASC;
 
 -- name: GetNextFeedToFetch :one
-SELECT * FROM feeds ORDER BY last_fetched_at ASC NULLS FIRST LIMIT 1;
\ No newline at end of file
+SELECT * FROM feeds ORDER BY last_fetched_at ASC NULLS FIRST LIMIT 1;
+
+-- name: GetNextFeedsToFetch :many
+UPDATE feeds
+SET last_fetched_at = $1, updated_at = $2
+WHERE id IN (
+    SELECT id FROM feeds
+    WHERE next_fetch_at IS NULL OR next_fetch_at <= $1
+    ORDER BY last_fetched_at ASC NULLS FIRST
+    LIMIT $3
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: DeleteFeeds :exec
+DELETE FROM feeds;
+
+-- name: RestoreFeed :exec
+INSERT INTO feeds(id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified,
+    last_error, consecutive_failures, last_success_at, next_fetch_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13);
\ No newline at end of file
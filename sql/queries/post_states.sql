@@ -0,0 +1,26 @@
+-- name: MarkPostRead :exec
+INSERT INTO post_states(user_id, post_id, read_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, post_id) DO UPDATE SET read_at = EXCLUDED.read_at;
+
+-- name: MarkPostUnread :exec
+UPDATE post_states SET read_at = NULL WHERE user_id = $1 AND post_id = $2;
+
+-- name: StarPost :exec
+INSERT INTO post_states(user_id, post_id, starred_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, post_id) DO UPDATE SET starred_at = EXCLUDED.starred_at;
+
+-- name: UnstarPost :exec
+UPDATE post_states SET starred_at = NULL WHERE user_id = $1 AND post_id = $2;
+
+-- name: GetPostStates :many
+SELECT * FROM post_states;
+
+-- name: GetPostStatesAfter :many
+SELECT * FROM post_states WHERE (user_id, post_id) > (sqlc.arg('after_user_id')::uuid, sqlc.arg('after_post_id')::uuid)
+ORDER BY user_id, post_id LIMIT sqlc.arg('limit');
+
+-- name: RestorePostState :exec
+INSERT INTO post_states(user_id, post_id, read_at, starred_at)
+VALUES ($1, $2, $3, $4);
\ No newline at end of file
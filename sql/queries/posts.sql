@@ -1,10 +1,107 @@
 -- name: CreatePost :one
 INSERT INTO posts(id, created_at, updated_at, title, url, description, published_at, feed_id) 
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING *;
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, guid;
+-- name: GetPostById :one
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, guid FROM posts WHERE id = $1;
+
 -- name: GetPostsByUser :many
-SELECT posts.* FROM posts
-INNER JOIN feeds
-ON posts.feed_id = feeds.id
-WHERE feeds.user_id = $1
-ORDER BY posts.published_at DESC
-LIMIT $2;
\ No newline at end of file
+-- Posts of the feeds followed by the user, newest first. A link published by
+-- several followed feeds is listed once, as its newest copy. Every filter is
+-- optional, the cursor is the (published_at, id) of the last post seen.
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, guid FROM (
+    SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.guid,
+        post_states.read_at, post_states.starred_at,
+        row_number() OVER (
+            PARTITION BY CASE WHEN posts.url = '' THEN posts.id::text ELSE posts.url END
+            ORDER BY posts.published_at DESC, posts.id DESC
+        ) AS copy_rank
+    FROM posts
+    INNER JOIN feed_follows
+    ON posts.feed_id = feed_follows.feed_id
+    LEFT JOIN post_states
+    ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+    WHERE feed_follows.user_id = sqlc.arg('user_id')
+        AND (sqlc.narg('feed_id')::uuid IS NULL OR posts.feed_id = sqlc.narg('feed_id'))
+        AND (sqlc.narg('since')::timestamp IS NULL OR posts.published_at >= sqlc.narg('since'))
+        AND (sqlc.narg('until')::timestamp IS NULL OR posts.published_at < sqlc.narg('until'))
+        AND (sqlc.narg('category')::text IS NULL OR feed_follows.category = sqlc.narg('category'))
+) AS timeline
+WHERE copy_rank = 1
+    AND (
+        sqlc.narg('cursor_published_at')::timestamp IS NULL
+        OR (published_at, id) < (sqlc.narg('cursor_published_at'), sqlc.narg('cursor_id')::uuid)
+    )
+    AND (NOT sqlc.arg('unread_only')::bool OR read_at IS NULL)
+    AND (NOT sqlc.arg('starred_only')::bool OR starred_at IS NOT NULL)
+ORDER BY published_at DESC, id DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: GetPostByGUID :one
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, guid FROM posts WHERE feed_id = $1 AND guid = $2;
+
+-- name: UpdatePost :exec
+UPDATE posts
+SET title = $1, url = $2, description = $3, updated_at = $4
+WHERE id = $5;
+
+-- name: UpsertPost :one
+-- Inserts a post or updates it when the feed already has one with the same
+-- url and its content changed. No row is returned when the post was left
+-- unchanged.
+INSERT INTO posts(id, created_at, updated_at, title, url, description, published_at, feed_id, guid)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+ON CONFLICT (feed_id, url) WHERE url <> '' DO UPDATE
+SET title = EXCLUDED.title,
+    description = EXCLUDED.description,
+    updated_at = EXCLUDED.updated_at,
+    guid = COALESCE(posts.guid, EXCLUDED.guid)
+WHERE posts.title IS DISTINCT FROM EXCLUDED.title
+    OR posts.description IS DISTINCT FROM EXCLUDED.description
+RETURNING (id = $1)::bool AS inserted;
+
+-- name: SearchPostsByUser :many
+-- Full text search over the posts of the feeds followed by the user, best
+-- matches first. The snippet highlights the matches between << and >>. The
+-- search vector is the expression of posts_search_idx, spelled the same so
+-- the index is used.
+SELECT
+    posts.id,
+    posts.title,
+    posts.url,
+    posts.published_at,
+    feeds.name AS feed_name,
+    ts_rank(
+        setweight(to_tsvector('english', coalesce(posts.title, '')), 'A') ||
+        setweight(to_tsvector('english', coalesce(posts.description, '')), 'B'),
+        query
+    )::real AS rank,
+    ts_headline(
+        'english',
+        coalesce(posts.description, posts.title),
+        query,
+        'StartSel=<<, StopSel=>>, MaxWords=35, MinWords=15, MaxFragments=2'
+    ) AS snippet
+FROM posts
+INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+INNER JOIN feeds ON posts.feed_id = feeds.id
+CROSS JOIN websearch_to_tsquery('english', sqlc.arg('query')) AS query
+WHERE feed_follows.user_id = sqlc.arg('user_id')
+    AND (
+        setweight(to_tsvector('english', coalesce(posts.title, '')), 'A') ||
+        setweight(to_tsvector('english', coalesce(posts.description, '')), 'B')
+    ) @@ query
+ORDER BY rank DESC, posts.published_at DESC
+LIMIT sqlc.arg('limit');
+
+-- name: GetPosts :many
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, guid FROM posts;
+
+-- name: GetPostsAfter :many
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, guid FROM posts WHERE id > sqlc.arg('after_id') ORDER BY id LIMIT sqlc.arg('limit');
+
+-- name: DeletePosts :exec
+DELETE FROM posts;
+
+-- name: RestorePost :exec
+INSERT INTO posts(id, created_at, updated_at, title, url, description, published_at, feed_id, guid)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9);
\ No newline at end of file
@@ -0,0 +1,11 @@
+-- name: CreateSession :exec
+INSERT INTO sessions(token_hash, user_id, created_at)
+VALUES ($1, $2, $3);
+
+-- name: GetUserBySession :one
+SELECT users.* FROM users
+JOIN sessions ON sessions.user_id = users.id
+WHERE sessions.token_hash = $1;
+
+-- name: DeleteUserSessions :exec
+DELETE FROM sessions WHERE user_id = $1;
@@ -0,0 +1,11 @@
+-- name: CreateSetupToken :exec
+INSERT INTO setup_tokens(token_hash, user_id, created_at)
+VALUES ($1, $2, $3);
+
+-- name: GetUserBySetupToken :one
+SELECT users.* FROM users
+JOIN setup_tokens ON setup_tokens.user_id = users.id
+WHERE setup_tokens.token_hash = $1;
+
+-- name: DeleteUserSetupTokens :exec
+DELETE FROM setup_tokens WHERE user_id = $1;
\ No newline at end of file
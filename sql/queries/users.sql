@@ -1,6 +1,6 @@
 -- name: CreateUser :one
-INSERT INTO users(id, created_at, updated_at, user_name) 
-VALUES ($1, $2, $3, $4) RETURNING *;
+INSERT INTO users(id, created_at, updated_at, user_name, password_hash, is_admin) 
+VALUES ($1, $2, $3, $4, $5, $6) RETURNING *;
 
 -- name: GetUserByName :one
 SELECT * from users where user_name = $1;
@@ -11,5 +11,18 @@ DELETE FROM users;
 -- name: GetUsers :many
 SELECT * FROM users;
 
+-- name: GetUsersAfter :many
+SELECT * FROM users WHERE id > sqlc.arg('after_id') ORDER BY id LIMIT sqlc.arg('limit');
+
 -- name: GetUserById :one
-SELECT * FROM users where id = $1;
\ No newline at end of file
+SELECT * FROM users where id = $1;
+
+-- name: SetUserPassword :exec
+UPDATE users SET password_hash = $2, updated_at = $3 WHERE id = $1;
+
+-- name: DeleteUser :exec
+DELETE FROM users WHERE id = $1;
+
+-- name: RestoreUser :exec
+INSERT INTO users(id, created_at, updated_at, user_name, password_hash, is_admin)
+VALUES ($1, $2, $3, $4, $5, $6);
\ No newline at end of file
@@ -0,0 +1,9 @@
+-- +goose Up
+-- Links are only unique within a feed, two feeds may publish the same post.
+-- Items without a link are told apart by their guid.
+ALTER TABLE posts DROP CONSTRAINT posts_url_key;
+CREATE UNIQUE INDEX posts_feed_id_url_idx ON posts(feed_id, url) WHERE url <> '';
+
+-- +goose Down
+DROP INDEX posts_feed_id_url_idx;
+ALTER TABLE posts ADD CONSTRAINT posts_url_key UNIQUE (url);
@@ -0,0 +1,8 @@
+-- +goose Up
+CREATE TABLE feed_tokens(
+	token_hash TEXT PRIMARY KEY,
+	user_id UUID NOT NULL UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+	created_at TIMESTAMP NOT NULL
+);
+-- +goose Down
+DROP TABLE feed_tokens;
\ No newline at end of file
@@ -0,0 +1,8 @@
+-- +goose Up
+CREATE TABLE setup_tokens(
+	token_hash TEXT PRIMARY KEY,
+	user_id UUID NOT NULL UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+	created_at TIMESTAMP NOT NULL
+);
+-- +goose Down
+DROP TABLE setup_tokens;
\ No newline at end of file
@@ -0,0 +1,17 @@
+-- +goose Up
+-- The search vector is indexed as an expression rather than stored as a
+-- column, so the posts table and the Post model only hold post data.
+DROP INDEX posts_search_vector_idx;
+ALTER TABLE posts DROP COLUMN search_vector;
+CREATE INDEX posts_search_idx ON posts USING GIN ((
+	setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+	setweight(to_tsvector('english', coalesce(description, '')), 'B')
+));
+
+-- +goose Down
+DROP INDEX posts_search_idx;
+ALTER TABLE posts ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+	setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+	setweight(to_tsvector('english', coalesce(description, '')), 'B')
+) STORED;
+CREATE INDEX posts_search_vector_idx ON posts USING GIN (search_vector);
\ No newline at end of file
@@ -0,0 +1,26 @@
+// Package schema embeds the goose migrations so the binary can create and
+// upgrade its own database with the migrate command.
+package schema
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed *.sql
+var Migrations embed.FS
+
+//go:embed sqlite/*.sql
+var sqliteMigrations embed.FS
+
+// SQLiteMigrations returns the migrations of the SQLite backend. They create
+// the same schema as the Postgres ones, version by version, so both backends
+// expect the same schema version.
+func SQLiteMigrations() fs.FS {
+	migrations, err := fs.Sub(sqliteMigrations, "sqlite")
+	if err != nil {
+		panic(err)
+	}
+
+	return migrations
+}
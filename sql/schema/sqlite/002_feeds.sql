@@ -0,0 +1,11 @@
+-- +goose Up
+CREATE TABLE feeds(
+	id TEXT PRIMARY KEY,
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL,
+	name TEXT NOT NULL,
+	url TEXT UNIQUE NOT NULL,
+	user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE
+);
+-- +goose Down
+DROP TABLE feeds;
\ No newline at end of file
@@ -0,0 +1,16 @@
+-- +goose Up
+-- seq is an explicit rowid so the full text index added in 010 keeps
+-- pointing to the right posts after a VACUUM.
+CREATE TABLE posts(
+	seq INTEGER PRIMARY KEY,
+	id TEXT UNIQUE NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL,
+	title TEXT NOT NULL,
+	url TEXT UNIQUE NOT NULL,
+	description TEXT,
+	published_at TIMESTAMP NOT NULL,
+	feed_id TEXT NOT NULL REFERENCES feeds(id) ON DELETE CASCADE
+);
+-- +goose Down
+DROP TABLE posts;
\ No newline at end of file
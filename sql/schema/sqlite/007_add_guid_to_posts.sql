@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE posts ADD COLUMN guid TEXT;
+CREATE UNIQUE INDEX posts_feed_id_guid_idx ON posts(feed_id, guid);
+
+-- +goose Down
+DROP INDEX posts_feed_id_guid_idx;
+ALTER TABLE posts DROP COLUMN guid;
\ No newline at end of file
@@ -0,0 +1,36 @@
+-- +goose Up
+-- FTS5 index over the title and description of the posts, kept in sync by
+-- the triggers below.
+CREATE VIRTUAL TABLE posts_search USING fts5(
+	title,
+	description,
+	content='posts',
+	content_rowid='seq',
+	tokenize='porter unicode61'
+);
+INSERT INTO posts_search(rowid, title, description) SELECT seq, title, description FROM posts;
+
+-- +goose StatementBegin
+CREATE TRIGGER posts_search_insert AFTER INSERT ON posts BEGIN
+	INSERT INTO posts_search(rowid, title, description) VALUES (new.seq, new.title, new.description);
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER posts_search_delete AFTER DELETE ON posts BEGIN
+	INSERT INTO posts_search(posts_search, rowid, title, description) VALUES ('delete', old.seq, old.title, old.description);
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER posts_search_update AFTER UPDATE OF title, description ON posts BEGIN
+	INSERT INTO posts_search(posts_search, rowid, title, description) VALUES ('delete', old.seq, old.title, old.description);
+	INSERT INTO posts_search(rowid, title, description) VALUES (new.seq, new.title, new.description);
+END;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TRIGGER posts_search_update;
+DROP TRIGGER posts_search_delete;
+DROP TRIGGER posts_search_insert;
+DROP TABLE posts_search;
\ No newline at end of file
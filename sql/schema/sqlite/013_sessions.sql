@@ -0,0 +1,8 @@
+-- +goose Up
+CREATE TABLE sessions(
+	token_hash TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	created_at TIMESTAMP NOT NULL
+);
+-- +goose Down
+DROP TABLE sessions;
\ No newline at end of file
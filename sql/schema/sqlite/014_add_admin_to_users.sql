@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN is_admin BOOLEAN NOT NULL DEFAULT FALSE;
+-- The oldest user administers the databases created before admins existed.
+UPDATE users SET is_admin = TRUE WHERE id = (SELECT id FROM users ORDER BY created_at LIMIT 1);
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN is_admin;
\ No newline at end of file
@@ -0,0 +1,92 @@
+-- +goose Up
+-- Links are only unique within a feed, two feeds may publish the same post.
+-- Items without a link are told apart by their guid.
+-- SQLite can't drop the UNIQUE constraint of url, so the table is rebuilt.
+-- The implicit delete of DROP TABLE doesn't fire the triggers, seq is kept so
+-- the full text index still matches, but it cascades to post_states, which
+-- are copied aside and put back.
+CREATE TEMP TABLE post_states_copy AS SELECT * FROM post_states;
+
+CREATE TABLE posts_new(
+	seq INTEGER PRIMARY KEY,
+	id TEXT UNIQUE NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL,
+	title TEXT NOT NULL,
+	url TEXT NOT NULL,
+	description TEXT,
+	published_at TIMESTAMP NOT NULL,
+	feed_id TEXT NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+	guid TEXT
+);
+INSERT INTO posts_new(seq, id, created_at, updated_at, title, url, description, published_at, feed_id, guid)
+SELECT seq, id, created_at, updated_at, title, url, description, published_at, feed_id, guid FROM posts;
+DROP TABLE posts;
+ALTER TABLE posts_new RENAME TO posts;
+CREATE UNIQUE INDEX posts_feed_id_guid_idx ON posts(feed_id, guid);
+CREATE UNIQUE INDEX posts_feed_id_url_idx ON posts(feed_id, url) WHERE url <> '';
+
+-- +goose StatementBegin
+CREATE TRIGGER posts_search_insert AFTER INSERT ON posts BEGIN
+	INSERT INTO posts_search(rowid, title, description) VALUES (new.seq, new.title, new.description);
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER posts_search_delete AFTER DELETE ON posts BEGIN
+	INSERT INTO posts_search(posts_search, rowid, title, description) VALUES ('delete', old.seq, old.title, old.description);
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER posts_search_update AFTER UPDATE OF title, description ON posts BEGIN
+	INSERT INTO posts_search(posts_search, rowid, title, description) VALUES ('delete', old.seq, old.title, old.description);
+	INSERT INTO posts_search(rowid, title, description) VALUES (new.seq, new.title, new.description);
+END;
+-- +goose StatementEnd
+
+INSERT INTO post_states SELECT * FROM post_states_copy;
+DROP TABLE post_states_copy;
+
+-- +goose Down
+CREATE TEMP TABLE post_states_copy AS SELECT * FROM post_states;
+
+CREATE TABLE posts_new(
+	seq INTEGER PRIMARY KEY,
+	id TEXT UNIQUE NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL,
+	title TEXT NOT NULL,
+	url TEXT UNIQUE NOT NULL,
+	description TEXT,
+	published_at TIMESTAMP NOT NULL,
+	feed_id TEXT NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+	guid TEXT
+);
+INSERT INTO posts_new(seq, id, created_at, updated_at, title, url, description, published_at, feed_id, guid)
+SELECT seq, id, created_at, updated_at, title, url, description, published_at, feed_id, guid FROM posts;
+DROP TABLE posts;
+ALTER TABLE posts_new RENAME TO posts;
+CREATE UNIQUE INDEX posts_feed_id_guid_idx ON posts(feed_id, guid);
+
+-- +goose StatementBegin
+CREATE TRIGGER posts_search_insert AFTER INSERT ON posts BEGIN
+	INSERT INTO posts_search(rowid, title, description) VALUES (new.seq, new.title, new.description);
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER posts_search_delete AFTER DELETE ON posts BEGIN
+	INSERT INTO posts_search(posts_search, rowid, title, description) VALUES ('delete', old.seq, old.title, old.description);
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER posts_search_update AFTER UPDATE OF title, description ON posts BEGIN
+	INSERT INTO posts_search(posts_search, rowid, title, description) VALUES ('delete', old.seq, old.title, old.description);
+	INSERT INTO posts_search(rowid, title, description) VALUES (new.seq, new.title, new.description);
+END;
+-- +goose StatementEnd
+
+INSERT INTO post_states SELECT * FROM post_states_copy;
+DROP TABLE post_states_copy;
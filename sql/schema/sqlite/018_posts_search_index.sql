@@ -0,0 +1,6 @@
+-- The Postgres search vector moves from a column of posts to an expression
+-- index. The SQLite posts are already searched through the posts_search FTS5
+-- table, this version only keeps both backends at the same schema version.
+-- +goose Up
+
+-- +goose Down